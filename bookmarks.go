@@ -0,0 +1,576 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bookmarkEntry is one line on the bookmarks page, either a
+// subfolder of the folder being browsed or a bookmark
+type bookmarkEntry struct {
+	folder string // full folder path, blank for bookmarks
+	bm     *BookMark
+}
+
+// normalizeFolder trims blanks and stray slashes from each level of
+// a "work/internal" style folder path. The top level folder is "".
+func normalizeFolder(folder string) string {
+	levels := []string{}
+	for _, level := range strings.Split(folder, "/") {
+		level = strings.TrimSpace(level)
+		if level != "" {
+			levels = append(levels, level)
+		}
+	}
+	return strings.Join(levels, "/")
+}
+
+// parentFolder returns the folder one level up, "" for the top
+func parentFolder(folder string) string {
+	i := strings.LastIndex(folder, "/")
+	if i < 0 {
+		return ""
+	}
+	return folder[:i]
+}
+
+// normalizeTags lowercases, trims and de-duplicates tags
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	normal := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normal = append(normal, tag)
+	}
+	sort.Strings(normal)
+	return normal
+}
+
+// normalizeUgri makes UGRIs that point at the same page compare equal
+// regardless of case in the server name or a missing protocol
+func normalizeUgri(ugri string) string {
+	ugri = strings.TrimSpace(ugri)
+	link, err := linkFromString(ugri)
+	if err != nil {
+		return strings.ToLower(ugri)
+	}
+	proto := "ugtp://"
+	if link.Secure {
+		proto = "ugtps://"
+	}
+	return fmt.Sprintf("%s%s:%s/%s",
+		proto, strings.ToLower(link.Server), link.Port, link.PageName)
+}
+
+// matches reports whether every word of the query appears in the
+// bookmark's name, UGRI, folder, description or one of its tags
+func (bm *BookMark) matches(query string) bool {
+	haystack := strings.ToLower(strings.Join([]string{
+		*bm.ShortName, *bm.Ugri, bm.Folder, bm.Description,
+		strings.Join(bm.Tags, " ")}, " "))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(haystack, strings.TrimPrefix(word, "#")) {
+			return false
+		}
+	}
+	return true
+}
+
+// details is the second line shown for a bookmark on the bookmarks
+// page. Search results also show the folder since they mix folders.
+func (bm *BookMark) details(showFolder bool) string {
+	details := []string{}
+	if showFolder && bm.Folder != "" {
+		details = append(details, fmt.Sprintf("[%s/]", bm.Folder))
+	}
+	for _, tag := range bm.Tags {
+		details = append(details, "#"+tag)
+	}
+	if bm.Description != "" {
+		details = append(details, bm.Description)
+	}
+	if visited, err := time.Parse(time.RFC1123, bm.LastVisited); err == nil {
+		details = append(details, "visited "+visited.Format("2006-01-02 15:04"))
+	}
+	return strings.Join(details, "  ")
+}
+
+// bookmarkEntries lists what the bookmarks page shows. With a query
+// it's every matching bookmark in any folder, otherwise the subfolders
+// of the folder followed by the bookmarks filed directly in it.
+func (s *ugglyBrowserSettings) bookmarkEntries(folder, query string) []bookmarkEntry {
+	entries := []bookmarkEntry{}
+	if strings.TrimSpace(query) != "" {
+		for _, bm := range s.Bookmarks {
+			if bm.matches(query) {
+				entries = append(entries, bookmarkEntry{bm: bm})
+			}
+		}
+		return entries
+	}
+	subfolders := make(map[string]bool)
+	prefix := ""
+	if folder != "" {
+		prefix = folder + "/"
+	}
+	for _, bm := range s.Bookmarks {
+		if bm.Folder == folder {
+			entries = append(entries, bookmarkEntry{bm: bm})
+			continue
+		}
+		if strings.HasPrefix(bm.Folder, prefix) {
+			sub := strings.SplitN(strings.TrimPrefix(bm.Folder, prefix), "/", 2)[0]
+			subfolders[prefix+sub] = true
+		}
+	}
+	folders := []bookmarkEntry{}
+	for f := range subfolders {
+		folders = append(folders, bookmarkEntry{folder: f})
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].folder < folders[j].folder
+	})
+	return append(folders, entries...)
+}
+
+// bookmarkVisited stamps the LastVisited time on any bookmark for the
+// page that was just loaded
+func (b *ugglyBrowser) bookmarkVisited() {
+	ugri := normalizeUgri(*b.sess.genUgri())
+	found := false
+	b.settingsMu.Lock()
+	for _, bm := range b.settings.Bookmarks {
+		if normalizeUgri(*bm.Ugri) == ugri {
+			bm.LastVisited = time.Now().Format(time.RFC1123)
+			found = true
+		}
+	}
+	b.settingsMu.Unlock()
+	if !found {
+		return
+	}
+	err := b.settingsSave()
+	if err != nil {
+		loggo.Error("error saving bookmark visit", "err", err.Error())
+	}
+}
+
+func (b *ugglyBrowser) bookmarksPage() {
+	thisfunc := "bookmarksPage"
+	loggo.Info("building bookmarks page")
+	b.bookmarkList = b.settings.bookmarkEntries(b.bookmarkFolder, b.bookmarkQuery)
+	perPage := bookmarksPerPage(b.vH)
+	if b.bookmarkOffset >= len(b.bookmarkList) {
+		b.bookmarkOffset = (len(b.bookmarkList) - 1) / perPage * perPage
+	}
+	if b.bookmarkOffset < 0 {
+		b.bookmarkOffset = 0
+	}
+	b.currentPage = buildBookmarks(b.vW, b.vH, b.bookmarkList,
+		b.bookmarkFolder, b.bookmarkQuery, b.bookmarkOffset)
+	b.currentPageLocal = b.currentPage
+	go b.sendMessage("Bookmarks Browser", thisfunc)
+	b.handle(b.buildDraw(thisfunc))
+}
+
+// bookmarkLinkRouter handles the folder and paging links from the
+// bookmarks page, links look like "bookmarks_<action>_<index>_<uuid>"
+func (b *ugglyBrowser) bookmarkLinkRouter(pageName string) {
+	chunks := strings.Split(pageName, "_")
+	if len(chunks) < 3 {
+		return
+	}
+	switch chunks[1] {
+	case "folder":
+		index, err := strconv.Atoi(chunks[2])
+		if err != nil || index < 0 || index >= len(b.bookmarkList) ||
+			b.bookmarkList[index].bm != nil {
+			return
+		}
+		b.bookmarkFolder = b.bookmarkList[index].folder
+		b.bookmarkOffset = 0
+	case "up":
+		b.bookmarkFolder = parentFolder(b.bookmarkFolder)
+		b.bookmarkOffset = 0
+	case "next":
+		b.bookmarkOffset += bookmarksPerPage(b.vH)
+	case "prev":
+		b.bookmarkOffset -= bookmarksPerPage(b.vH)
+	}
+	b.bookmarksPage()
+}
+
+// bookmarkSearch filters the bookmarks page, a blank query goes back
+// to browsing folders
+func (b *ugglyBrowser) bookmarkSearch(formContents map[string]string) {
+	b.bookmarkQuery = strings.TrimSpace(formContents["Query"])
+	b.bookmarkOffset = 0
+	b.bookmarksPage()
+}
+
+// bookmarkFile is the portable format bookmarks are exported to and
+// imported from as YAML or JSON
+type bookmarkFile struct {
+	Bookmarks []*BookMark `yaml:"bookMarks" json:"bookMarks"`
+}
+
+var (
+	netscapeFolderRegex = regexp.MustCompile(`(?i)<DT>\s*<H3[^>]*>(.*?)</H3>`)
+	netscapeLinkRegex   = regexp.MustCompile(`(?i)<DT>\s*<A\s+([^>]*)>(.*?)</A>`)
+	netscapeAttrRegex   = regexp.MustCompile(`([A-Za-z_]+)="([^"]*)"`)
+	netscapeDescRegex   = regexp.MustCompile(`(?i)<DD>(.*)`)
+	netscapeCloseRegex  = regexp.MustCompile(`(?i)</DL>`)
+)
+
+// bookmarkFormat picks the file format from the file's extension
+func bookmarkFormat(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yml", ".yaml":
+		return "yaml", nil
+	case ".json":
+		return "json", nil
+	case ".html", ".htm":
+		return "netscape", nil
+	}
+	return "", fmt.Errorf("unknown bookmark file format for '%s', "+
+		"use .yml, .json or .html", filename)
+}
+
+// folderLevels splits a folder path so folders sort level by level
+func folderLevels(folder string) []string {
+	if folder == "" {
+		return []string{}
+	}
+	return strings.Split(folder, "/")
+}
+
+// writeNetscapeBookmarks writes bookmarks in the Netscape bookmark file
+// format most browsers can import, folders become nested lists
+func writeNetscapeBookmarks(w io.Writer, bms []*BookMark) {
+	sorted := append([]*BookMark{}, bms...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := folderLevels(sorted[i].Folder), folderLevels(sorted[j].Folder)
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	fmt.Fprint(w, "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n"+
+		"<TITLE>Bookmarks</TITLE>\n<H1>Bookmarks</H1>\n<DL><p>\n")
+	open := []string{}
+	for _, bm := range sorted {
+		levels := folderLevels(bm.Folder)
+		common := 0
+		for common < len(open) && common < len(levels) && open[common] == levels[common] {
+			common++
+		}
+		for len(open) > common {
+			open = open[:len(open)-1]
+			fmt.Fprintf(w, "%s</DL><p>\n", strings.Repeat("    ", len(open)+1))
+		}
+		for _, level := range levels[common:] {
+			indent := strings.Repeat("    ", len(open)+1)
+			fmt.Fprintf(w, "%s<DT><H3>%s</H3>\n%s<DL><p>\n",
+				indent, html.EscapeString(level), indent)
+			open = append(open, level)
+		}
+		attrs := fmt.Sprintf(`HREF="%s"`, html.EscapeString(*bm.Ugri))
+		if len(bm.Tags) > 0 {
+			attrs += fmt.Sprintf(` TAGS="%s"`, html.EscapeString(strings.Join(bm.Tags, ",")))
+		}
+		if visited, err := time.Parse(time.RFC1123, bm.LastVisited); err == nil {
+			attrs += fmt.Sprintf(` LAST_VISIT="%d"`, visited.Unix())
+		}
+		indent := strings.Repeat("    ", len(open)+1)
+		fmt.Fprintf(w, "%s<DT><A %s>%s</A>\n", indent, attrs, html.EscapeString(*bm.ShortName))
+		if bm.Description != "" {
+			fmt.Fprintf(w, "%s<DD>%s\n", indent, html.EscapeString(bm.Description))
+		}
+	}
+	for len(open) > 0 {
+		open = open[:len(open)-1]
+		fmt.Fprintf(w, "%s</DL><p>\n", strings.Repeat("    ", len(open)+1))
+	}
+	fmt.Fprint(w, "</DL><p>\n")
+}
+
+// readNetscapeBookmarks reads the links out of a Netscape bookmark file
+// one line at a time, which is how browsers write them
+func readNetscapeBookmarks(dat []byte) []*BookMark {
+	bms := []*BookMark{}
+	open := []string{}
+	var last *BookMark
+	for _, line := range strings.Split(string(dat), "\n") {
+		if m := netscapeFolderRegex.FindStringSubmatch(line); m != nil {
+			open = append(open, strings.ReplaceAll(html.UnescapeString(m[1]), "/", "-"))
+			last = nil
+			continue
+		}
+		if m := netscapeLinkRegex.FindStringSubmatch(line); m != nil {
+			bm := &BookMark{Folder: strings.Join(open, "/")}
+			for _, attr := range netscapeAttrRegex.FindAllStringSubmatch(m[1], -1) {
+				value := html.UnescapeString(attr[2])
+				switch strings.ToUpper(attr[1]) {
+				case "HREF":
+					bm.Ugri = &value
+				case "TAGS":
+					bm.Tags = splitList(value)
+				case "LAST_VISIT":
+					if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+						bm.LastVisited = time.Unix(secs, 0).Format(time.RFC1123)
+					}
+				}
+			}
+			name := html.UnescapeString(m[2])
+			bm.ShortName = &name
+			bms = append(bms, bm)
+			last = bm
+			continue
+		}
+		if m := netscapeDescRegex.FindStringSubmatch(line); m != nil && last != nil {
+			last.Description = strings.TrimSpace(html.UnescapeString(m[1]))
+			continue
+		}
+		if netscapeCloseRegex.MatchString(line) && len(open) > 0 {
+			open = open[:len(open)-1]
+		}
+	}
+	return bms
+}
+
+// exportBookmarks writes the bookmarks in folder and its subfolders, or
+// all of them if folder is blank, to filename. Folders are written
+// relative to folder so the list can be imported anywhere.
+func (b *ugglyBrowser) exportBookmarks(filename, folder string) (count int, err error) {
+	format, err := bookmarkFormat(filename)
+	if err != nil {
+		return 0, err
+	}
+	folder = normalizeFolder(folder)
+	export := bookmarkFile{Bookmarks: []*BookMark{}}
+	b.settingsMu.Lock()
+	for _, bm := range b.settings.Bookmarks {
+		if folder != "" && bm.Folder != folder && !strings.HasPrefix(bm.Folder, folder+"/") {
+			continue
+		}
+		copied := *bm
+		copied.uid = nil
+		copied.Folder = strings.TrimPrefix(strings.TrimPrefix(bm.Folder, folder), "/")
+		export.Bookmarks = append(export.Bookmarks, &copied)
+	}
+	b.settingsMu.Unlock()
+	var dat []byte
+	switch format {
+	case "yaml":
+		dat, err = yaml.Marshal(&export)
+	case "json":
+		dat, err = json.MarshalIndent(&export, "", "  ")
+	case "netscape":
+		var buf bytes.Buffer
+		writeNetscapeBookmarks(&buf, export.Bookmarks)
+		dat = buf.Bytes()
+	}
+	if err != nil {
+		return 0, err
+	}
+	err = ioutil.WriteFile(filename, dat, 0644)
+	if err != nil {
+		return 0, err
+	}
+	loggo.Info("exported bookmarks", "file", filename, "num_bookmarks", len(export.Bookmarks))
+	return len(export.Bookmarks), err
+}
+
+// importBookmarks adds the bookmarks in filename under folder. Bookmarks
+// whose normalized UGRI is already bookmarked, or appears earlier in the
+// file, are skipped as duplicates and ones without a valid UGRI are
+// skipped as invalid.
+func (b *ugglyBrowser) importBookmarks(filename, folder string) (added, duplicates, invalid int, err error) {
+	format, err := bookmarkFormat(filename)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	dat, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	imported := bookmarkFile{}
+	switch format {
+	case "yaml":
+		err = yaml.Unmarshal(dat, &imported)
+	case "json":
+		err = json.Unmarshal(dat, &imported)
+	case "netscape":
+		imported.Bookmarks = readNetscapeBookmarks(dat)
+	}
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("'%s' is not a bookmark export: %s", filename, err.Error())
+	}
+	b.settingsMu.Lock()
+	known := make(map[string]bool)
+	for _, bm := range b.settings.Bookmarks {
+		known[normalizeUgri(*bm.Ugri)] = true
+	}
+	folder = normalizeFolder(folder)
+	for _, bm := range imported.Bookmarks {
+		if bm == nil || bm.Ugri == nil || strings.TrimSpace(*bm.Ugri) == "" {
+			invalid++
+			continue
+		}
+		trimmed := strings.TrimSpace(*bm.Ugri)
+		bm.Ugri = &trimmed
+		if _, err := linkFromString(*bm.Ugri); err != nil {
+			invalid++
+			continue
+		}
+		ugri := normalizeUgri(*bm.Ugri)
+		if known[ugri] {
+			duplicates++
+			continue
+		}
+		known[ugri] = true
+		if bm.ShortName == nil || strings.TrimSpace(*bm.ShortName) == "" {
+			shortName := *bm.Ugri
+			bm.ShortName = &shortName
+		}
+		bm.Folder = normalizeFolder(folder + "/" + bm.Folder)
+		bm.Tags = normalizeTags(bm.Tags)
+		if _, err := time.Parse(time.RFC1123, bm.LastVisited); err != nil {
+			bm.LastVisited = ""
+		}
+		b.settings.Bookmarks = append(b.settings.Bookmarks, bm)
+		added++
+	}
+	b.settings.uidifyBookmarks()
+	b.settingsMu.Unlock()
+	loggo.Info("imported bookmarks", "file", filename,
+		"added", added, "duplicates", duplicates, "invalid", invalid)
+	if added == 0 {
+		return added, duplicates, invalid, nil
+	}
+	return added, duplicates, invalid, b.settingsSave()
+}
+
+// bookmarkTransfer runs the -bookmarks-export and -bookmarks-import
+// modes without starting the browser. Returns the exit code.
+func (b *ugglyBrowser) bookmarkTransfer(exportFile, importFile, folder string) int {
+	if exportFile != "" {
+		count, err := b.exportBookmarks(exportFile, folder)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error exporting bookmarks: %s\n", err.Error())
+			return 1
+		}
+		fmt.Printf("exported %d bookmarks to '%s'\n", count, exportFile)
+	}
+	if importFile != "" {
+		added, duplicates, invalid, err := b.importBookmarks(importFile, folder)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error importing bookmarks: %s\n", err.Error())
+			return 1
+		}
+		fmt.Printf("imported %d bookmarks into '%s', skipped %d duplicates "+
+			"and %d without a valid ugri\n", added, b.settingsFile, duplicates, invalid)
+	}
+	return 0
+}
+
+// bookmarkTransferProcess handles the export and import forms on the
+// settings page
+func (b *ugglyBrowser) bookmarkTransferProcess(export bool, formContents map[string]string) {
+	filename := strings.TrimSpace(formContents["BookmarkFile"])
+	folder := formContents["BookmarkFolder"]
+	var infoMsg string
+	if filename == "" {
+		infoMsg = "a file name is required"
+	} else if export {
+		count, err := b.exportBookmarks(filename, folder)
+		if err != nil {
+			loggo.Error("error exporting bookmarks", "err", err.Error())
+			infoMsg = fmt.Sprintf("error exporting bookmarks to '%s': %s", filename, err.Error())
+		} else {
+			infoMsg = fmt.Sprintf("exported %d bookmarks to '%s'", count, filename)
+		}
+	} else {
+		added, duplicates, invalid, err := b.importBookmarks(filename, folder)
+		if err != nil {
+			loggo.Error("error importing bookmarks", "err", err.Error())
+			infoMsg = fmt.Sprintf("error importing bookmarks from '%s': %s", filename, err.Error())
+		} else {
+			infoMsg = fmt.Sprintf("imported %d bookmarks from '%s', skipped %d "+
+				"duplicates and %d invalid", added, filename, duplicates, invalid)
+		}
+	}
+	b.sendMessage(infoMsg, "bookmark-transfer")
+	b.settingsPage(infoMsg)
+}
+
+// bookmarkAdd asks the user to name, tag and file the current page
+// before bookmarking it. A page that's already bookmarked is edited.
+func (b *ugglyBrowser) bookmarkAdd() {
+	thisfunc := "bookmarkAdd"
+	if b.currentPageLocal != nil {
+		go b.sendMessage("local pages can't be bookmarked", thisfunc)
+		return
+	}
+	ugri := *b.sess.genUgri()
+	draft := &BookMark{Ugri: &ugri, Folder: b.bookmarkFolder}
+	b.settingsMu.Lock()
+	existing := b.settings.findBookmark(ugri)
+	if existing != nil {
+		copied := *existing
+		draft = &copied
+	}
+	b.settingsMu.Unlock()
+	if existing == nil {
+		// servers name their pages so that's the best title we have
+		shortName := b.sess.currPage
+		if b.currentPage != nil && b.currentPage.Name != "" {
+			shortName = b.currentPage.Name
+		}
+		draft.ShortName = &shortName
+	}
+	values, ok := b.promptForm(buildBookmarkForm(b.vW, b.vH+b.menuHeight, draft, existing != nil))
+	if !ok {
+		go b.sendMessage("bookmark not saved", thisfunc)
+		return
+	}
+	b.settingsMu.Lock()
+	// look it up again, the settings may have been reloaded from disk
+	// while the form was up
+	bm := b.settings.findBookmark(ugri)
+	if bm == nil {
+		bm = b.settings.addBookmark(strings.TrimSpace(values["ShortName"]), ugri)
+	} else if shortName := strings.TrimSpace(values["ShortName"]); shortName != "" {
+		bm.ShortName = &shortName
+	}
+	bm.Tags = normalizeTags(splitList(values["Tags"]))
+	bm.Folder = normalizeFolder(values["Folder"])
+	bm.Description = strings.TrimSpace(values["Description"])
+	message := fmt.Sprintf("saved bookmark '%s' in /%s", *bm.ShortName, bm.Folder)
+	loggo.Info("saving bookmark", "ugri", ugri, "folder", bm.Folder)
+	b.settingsMu.Unlock()
+	err := b.settingsSave()
+	if err != nil {
+		loggo.Error("error adding bookmark", "err", err.Error())
+		message = "error adding bookmark, check log"
+	}
+	go b.sendMessage(message, thisfunc)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func bookmark(name, ugri, folder string) *BookMark {
+	return &BookMark{ShortName: &name, Ugri: &ugri, Folder: folder}
+}
+
+func TestNormalizeUgri(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"ugtp://server:8888/page", "ugtp://server:8888/page"},
+		{"ugtp://SERVER:8888/page", "ugtp://server:8888/page"},
+		{"  ugtp://server:8888/page\n", "ugtp://server:8888/page"},
+		{"ugtps://Server:4443/Page", "ugtps://server:4443/Page"},
+		{"ugtp://server:8888/", "ugtp://server:8888/"},
+		{"Not A UGRI", "not a ugri"},
+	}
+	for _, tt := range tests {
+		if got := normalizeUgri(tt.in); got != tt.want {
+			t.Errorf("normalizeUgri(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNetscapeBookmarksRoundTrip(t *testing.T) {
+	visited := time.Unix(1600000000, 0).Format(time.RFC1123)
+	tagged := bookmark("tagged & <quoted>", "ugtp://server:8888/tags?a=\"b\"", "")
+	tagged.Tags = []string{"one", "two"}
+	tagged.Description = "described"
+	tagged.LastVisited = visited
+	tests := []struct {
+		name string
+		bms  []*BookMark
+	}{
+		{"empty", []*BookMark{}},
+		{"top level", []*BookMark{bookmark("home", "ugtp://server:8888/home", "")}},
+		{"attributes", []*BookMark{tagged}},
+		{"nested folders", []*BookMark{
+			bookmark("a", "ugtp://server:8888/a", ""),
+			bookmark("b", "ugtp://server:8888/b", "work"),
+			bookmark("c", "ugtp://server:8888/c", "work/internal"),
+			bookmark("d", "ugtp://server:8888/d", "work"),
+			bookmark("e", "ugtp://server:8888/e", "play"),
+		}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		writeNetscapeBookmarks(&buf, tt.bms)
+		got := readNetscapeBookmarks(buf.Bytes())
+		byUgri := make(map[string]*BookMark)
+		for _, bm := range got {
+			byUgri[*bm.Ugri] = bm
+		}
+		if len(got) != len(tt.bms) {
+			t.Errorf("%s: read %d bookmarks, wrote %d\n%s", tt.name, len(got), len(tt.bms), buf.String())
+			continue
+		}
+		for _, want := range tt.bms {
+			bm, ok := byUgri[*want.Ugri]
+			if !ok {
+				t.Errorf("%s: %s went missing", tt.name, *want.Ugri)
+				continue
+			}
+			if !reflect.DeepEqual(bm, want) {
+				t.Errorf("%s: read %+v, wrote %+v", tt.name, *bm, *want)
+			}
+		}
+	}
+}
+
+func TestReadNetscapeBookmarks(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		wantUgri   []string
+		wantFolder []string
+	}{
+		{"browser attributes", `<DL><p>
+    <DT><A HREF="ugtp://server:8888/a" ADD_DATE="1600000000" ICON="data:x">a</A>
+</DL><p>`, []string{"ugtp://server:8888/a"}, []string{""}},
+		{"lower case tags", `<dl><p>
+    <dt><h3 add_date="1">Work</h3>
+    <dl><p>
+        <dt><a href="ugtp://server:8888/a">a</a>
+    </dl><p>
+    <dt><a href="ugtp://server:8888/b">b</a>
+</dl><p>`, []string{"ugtp://server:8888/a", "ugtp://server:8888/b"}, []string{"Work", ""}},
+		{"slash in folder name", `<DL><p>
+    <DT><H3>a/b</H3>
+    <DL><p>
+        <DT><A HREF="ugtp://server:8888/a">a</A>
+    </DL><p>
+</DL><p>`, []string{"ugtp://server:8888/a"}, []string{"a-b"}},
+		{"not a bookmark file", "just some text", []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		bms := readNetscapeBookmarks([]byte(tt.file))
+		ugris, folders := []string{}, []string{}
+		for _, bm := range bms {
+			ugris = append(ugris, *bm.Ugri)
+			folders = append(folders, bm.Folder)
+		}
+		if !reflect.DeepEqual(ugris, tt.wantUgri) || !reflect.DeepEqual(folders, tt.wantFolder) {
+			t.Errorf("%s: read %v in %v, want %v in %v",
+				tt.name, ugris, folders, tt.wantUgri, tt.wantFolder)
+		}
+	}
+}
+
+func TestImportBookmarksDuplicates(t *testing.T) {
+	b := settingsBrowser(t, func(s *ugglyBrowserSettings) {
+		s.addBookmark("home", "ugtp://server:8888/home")
+	})
+	file := filepath.Join(t.TempDir(), "import.yml")
+	err := ioutil.WriteFile(file, []byte(`bookMarks:
+  - ugri: ugtp://SERVER:8888/home
+    shortName: already bookmarked
+  - ugri: ugtp://server:8888/new
+    shortName: new
+  - ugri: " ugtp://server:8888/new"
+    shortName: twice in the file
+  - ugri: ""
+    shortName: blank
+  - shortName: missing
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, duplicates, invalid, err := b.importBookmarks(file, "imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 || duplicates != 2 || invalid != 2 {
+		t.Errorf("added %d, duplicates %d, invalid %d, want 1, 2, 2", added, duplicates, invalid)
+	}
+	bm := b.settings.findBookmark("ugtp://server:8888/new")
+	if bm == nil || bm.Folder != "imported" {
+		t.Errorf("new bookmark not imported into its folder: %+v", bm)
+	}
+}
+
+func TestBookmarkFormFits(t *testing.T) {
+	for _, width := range []int{10, 20, 30, 80, 200} {
+		page := buildBookmarkForm(width, 24, bookmark("home", "ugtp://server:8888/home", ""), false)
+		div := page.DivBoxes.Boxes[0]
+		if div.Width < 1 || div.Width > int32(width) || div.StartX < 0 {
+			t.Errorf("width %d: modal is %d wide at %d", width, div.Width, div.StartX)
+		}
+		for _, tb := range page.Elements.Forms[0].TextBoxes {
+			if tb.Width < 1 {
+				t.Errorf("width %d: textbox %s is %d wide", width, tb.Name, tb.Width)
+			}
+		}
+	}
+}
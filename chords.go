@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	pb "github.com/rendicott/uggly"
+	"time"
+)
+
+// chordTimeout is posted to the event loop when a chord has waited
+// chordDelay for its next key
+type chordTimeout struct {
+	seq int // which pending chord timed out
+}
+
+func (c chordTimeout) When() time.Time {
+	return time.Now()
+}
+
+// matchChords compares the keys pressed so far plus the new key event
+// with every active keystroke. It returns the keystrokes the key
+// completes, the ones it's the start of and the key as it matched.
+func (b *ugglyBrowser) matchChords(pending []keySpec, ev *tcell.EventKey) (
+	complete, partial []*pb.KeyStroke, spec keySpec) {
+	for _, ks := range b.activeKeyStrokes {
+		chord, err := parseChord(ks.KeyStroke)
+		if err != nil {
+			loggo.Debug("ignoring keystroke that can't be parsed",
+				"keyStroke", ks.KeyStroke, "error", err.Error())
+			continue
+		}
+		if len(chord) <= len(pending) {
+			continue
+		}
+		prefix := true
+		for i := range pending {
+			if chord[i] != pending[i] {
+				prefix = false
+				break
+			}
+		}
+		next := chord[len(pending)]
+		if !prefix || !next.matches(ev) {
+			continue
+		}
+		spec = next
+		if len(chord) == len(pending)+1 {
+			complete = append(complete, ks)
+		} else {
+			partial = append(partial, ks)
+		}
+	}
+	return complete, partial, spec
+}
+
+// handleKeyStrokes finds the keystrokes a key press completes and routes
+// the first one, later keystrokes for the same keys are ignored so the
+// result doesn't depend on more than the order the server sent them in.
+// If the key is also the start of a longer chord it waits up to
+// chordDelay for the next key before settling for the shorter one.
+func (b *ugglyBrowser) handleKeyStrokes(ctx context.Context, ev *tcell.EventKey) {
+	if ev.Key() == tcell.KeyRune {
+		loggo.Debug("detected keypress", "key", string(ev.Rune()))
+	} else {
+		_, name := detectSpecialKey(ev)
+		loggo.Debug("detected keypress", "key", name)
+	}
+	loggo.Debug("checking activeKeyStrokes for expected keypresses",
+		"numLinks", len(b.activeKeyStrokes), "pending", chordString(b.chordPending))
+	complete, partial, spec := b.matchChords(b.chordPending, ev)
+	if len(complete) == 0 && len(partial) == 0 && len(b.chordPending) > 0 {
+		// the chord was broken off, the key may start something new
+		loggo.Debug("chord broken off", "pending", chordString(b.chordPending))
+		b.resetChord()
+		complete, partial, spec = b.matchChords(nil, ev)
+	}
+	if len(partial) > 0 {
+		b.chordPending = append(b.chordPending, spec)
+		b.chordComplete = complete
+		b.chordSeq++
+		seq := b.chordSeq
+		time.AfterFunc(b.chordDelay, func() {
+			b.view.PostEvent(chordTimeout{seq: seq})
+		})
+		go b.sendMessage(fmt.Sprintf("keys: %s ...", chordString(b.chordPending)), "chord")
+		return
+	}
+	b.resetChord()
+	if len(complete) > 0 {
+		loggo.Info("sending expected key to keyStroke router",
+			"keyStroke", complete[0].KeyStroke, "ignored", len(complete)-1)
+		b.keyStrokeRouter(ctx, complete[0])
+	}
+}
+
+// chordExpired runs when no key followed a pending chord in time. Any
+// keystroke the keys so far complete on their own is routed.
+func (b *ugglyBrowser) chordExpired(ctx context.Context, seq int) {
+	if seq != b.chordSeq || len(b.chordPending) == 0 {
+		return // a newer key already moved the chord along
+	}
+	pending := chordString(b.chordPending)
+	complete := b.chordComplete
+	b.resetChord()
+	if len(complete) == 0 {
+		go b.sendMessage(fmt.Sprintf("keys: %s not bound", pending), "chord")
+		return
+	}
+	b.keyStrokeRouter(ctx, complete[0])
+}
+
+// resetChord forgets any partially typed chord
+func (b *ugglyBrowser) resetChord() {
+	b.chordPending = nil
+	b.chordComplete = nil
+}
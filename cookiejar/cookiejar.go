@@ -0,0 +1,270 @@
+// cookiejar stores the cookies servers set and decides which of them
+// go out with each page request. The rules follow the spirit of the
+// HTTP cookie spec (RFC 6265bis) translated to uggly where a cookie's
+// Server plays the part of the domain and Page the part of the path.
+package cookiejar
+
+import (
+	pb "github.com/rendicott/uggly"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Request describes an outgoing page request
+type Request struct {
+	Server string
+	Page   string
+	Secure bool
+	// Initiator is the server of the page the request came from. Blank
+	// means the user asked for it directly (address bar, bookmark,
+	// local page) which counts as same-site.
+	Initiator string
+}
+
+func (r *Request) sameSite() bool {
+	return r.Initiator == "" || r.Initiator == r.Server
+}
+
+// Jar holds cookies keyed by the server that set them (the origin). A
+// cookie's own Server field names where it's sent and defaults to the
+// origin. Jars are safe for concurrent use.
+type Jar struct {
+	mu      sync.Mutex
+	cookies map[string][]*pb.Cookie
+}
+
+// New returns an empty jar
+func New() *Jar {
+	return &Jar{cookies: make(map[string][]*pb.Cookie)}
+}
+
+// Expired reports whether the cookie's Expires is in the past. Cookies
+// with a blank or unparseable Expires are session cookies and never
+// expire while the browser is running.
+func Expired(c *pb.Cookie, now time.Time) bool {
+	expires, err := time.Parse(time.RFC1123, c.Expires)
+	return err == nil && !now.Before(expires)
+}
+
+// Persistent reports whether the cookie should outlive the session
+func Persistent(c *pb.Cookie, now time.Time) bool {
+	_, err := time.Parse(time.RFC1123, c.Expires)
+	return err == nil && !Expired(c, now)
+}
+
+// sameCookie is the spec's identity: name, domain and path
+func sameCookie(a, b *pb.Cookie) bool {
+	return a.Key == b.Key && a.Server == b.Server && a.Page == b.Page
+}
+
+// Copy returns a copy of the cookie the jar can own
+func Copy(c *pb.Cookie) *pb.Cookie {
+	return &pb.Cookie{
+		Key:      c.Key,
+		Value:    c.Value,
+		Server:   c.Server,
+		Private:  c.Private,
+		Expires:  c.Expires,
+		SameSite: c.SameSite,
+		Page:     c.Page,
+		Secure:   c.Secure,
+		Metadata: c.Metadata,
+	}
+}
+
+// Set stores copies of cookies received from origin over a connection
+// that was secure or not. Cookies replace stored ones with the same Key,
+// Server and Page. Following the spec:
+//   - a past Expires deletes the stored cookie
+//   - Secure cookies can only be set over a secure connection
+//   - SameSite=None cookies must be Secure
+//
+// Cookies with no Key are rejected too. Returns the number of cookies
+// stored and rejected.
+func (j *Jar) Set(origin string, secure bool, cookies []*pb.Cookie, now time.Time) (stored, rejected int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cookies {
+		if c.Key == "" ||
+			(c.Secure && !secure) ||
+			(c.SameSite == pb.Cookie_NONE && !c.Secure) {
+			rejected++
+			continue
+		}
+		// the caller's cookie, e.g., from a PageResponse, is left alone
+		c = Copy(c)
+		if c.Server == "" {
+			// be nice and default to the origin which makes
+			// it easier on server devs
+			c.Server = origin
+		}
+		existing := j.cookies[origin]
+		kept := existing[:0]
+		for _, e := range existing {
+			if !sameCookie(e, c) {
+				kept = append(kept, e)
+			}
+		}
+		if Expired(c, now) {
+			// servers delete cookies by expiring them
+			j.update(origin, kept)
+			continue
+		}
+		j.update(origin, append(kept, c))
+		stored++
+	}
+	return stored, rejected
+}
+
+// update replaces an origin's cookies dropping the origin when empty
+func (j *Jar) update(origin string, cookies []*pb.Cookie) {
+	if len(cookies) == 0 {
+		delete(j.cookies, origin)
+		return
+	}
+	j.cookies[origin] = cookies
+}
+
+// Cookies returns the cookies that should be sent with the request
+// ordered by origin and then by the order they were set
+func (j *Jar) Cookies(req *Request, now time.Time) []*pb.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	send := []*pb.Cookie{}
+	for _, origin := range j.origins() {
+		for _, c := range j.cookies[origin] {
+			if allowed(origin, c, req, now) {
+				send = append(send, c)
+			}
+		}
+	}
+	return send
+}
+
+// allowed is the rule set deciding whether a stored cookie set by
+// origin goes out with the request
+func allowed(origin string, c *pb.Cookie, req *Request, now time.Time) bool {
+	switch {
+	case c.Server != req.Server:
+		return false
+	case c.Private:
+		// private cookies never leave the browser
+		return false
+	case Expired(c, now):
+		return false
+	case c.Secure && !req.Secure:
+		return false
+	case c.Page != "" && c.Page != req.Page:
+		return false
+	}
+	if origin != c.Server && c.SameSite != pb.Cookie_NONE {
+		// a third party cookie, set by one server for another,
+		// is cross-site by definition
+		return false
+	}
+	switch c.SameSite {
+	case pb.Cookie_STRICT:
+		return req.sameSite()
+	case pb.Cookie_NONE:
+		// None cookies loaded from older jars may not be Secure
+		return req.Secure
+	}
+	return false
+}
+
+// Load replaces the cookies for an origin, e.g., from disk, without
+// applying any of the rules Set uses. Like Set it stores copies.
+func (j *Jar) Load(origin string, cookies []*pb.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	copies := make([]*pb.Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		if c != nil {
+			copies = append(copies, Copy(c))
+		}
+	}
+	j.update(origin, copies)
+}
+
+// origins returns the origins in a stable order. Callers hold the lock.
+func (j *Jar) origins() []string {
+	origins := make([]string, 0, len(j.cookies))
+	for origin := range j.cookies {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	return origins
+}
+
+// Servers returns every origin holding cookies in a stable order
+func (j *Jar) Servers() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.origins()
+}
+
+// All returns a copy of every origin's cookies
+func (j *Jar) All() map[string][]*pb.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	all := make(map[string][]*pb.Cookie, len(j.cookies))
+	for origin, cookies := range j.cookies {
+		all[origin] = append([]*pb.Cookie{}, cookies...)
+	}
+	return all
+}
+
+// Persistent returns a copy of the cookies that should be written to
+// disk, that is every unexpired cookie with an Expires
+func (j *Jar) Persistent(now time.Time) map[string][]*pb.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	keep := make(map[string][]*pb.Cookie)
+	for origin, cookies := range j.cookies {
+		for _, c := range cookies {
+			if Persistent(c, now) {
+				keep[origin] = append(keep[origin], c)
+			}
+		}
+	}
+	return keep
+}
+
+// Count returns the number of cookies in the jar
+func (j *Jar) Count() (count int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, cookies := range j.cookies {
+		count += len(cookies)
+	}
+	return count
+}
+
+// Delete removes the origin's cookie at index, in the order All
+// returns them, and returns it
+func (j *Jar) Delete(origin string, index int) (*pb.Cookie, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cookies := j.cookies[origin]
+	if index < 0 || index >= len(cookies) {
+		return nil, false
+	}
+	c := cookies[index]
+	j.update(origin, append(cookies[:index:index], cookies[index+1:]...))
+	return c, true
+}
+
+// ClearServer removes every cookie set by origin
+func (j *Jar) ClearServer(origin string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.cookies, origin)
+}
+
+// Clear empties the jar
+func (j *Jar) Clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cookies = make(map[string][]*pb.Cookie)
+}
@@ -0,0 +1,315 @@
+package cookiejar
+
+import (
+	pb "github.com/rendicott/uggly"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func future() string {
+	return now.Add(time.Hour).Format(time.RFC1123)
+}
+
+func past() string {
+	return now.Add(-time.Hour).Format(time.RFC1123)
+}
+
+func keys(cookies []*pb.Cookie) []string {
+	k := []string{}
+	for _, c := range cookies {
+		k = append(k, c.Key)
+	}
+	return k
+}
+
+func sameKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestExpired(t *testing.T) {
+	tests := []struct {
+		name       string
+		expires    string
+		expired    bool
+		persistent bool
+	}{
+		{"session cookie", "", false, false},
+		{"unparseable", "tomorrow", false, false},
+		{"future", future(), false, true},
+		{"past", past(), true, false},
+		{"exactly now", now.Format(time.RFC1123), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &pb.Cookie{Key: "k", Expires: tt.expires}
+			if got := Expired(c, now); got != tt.expired {
+				t.Errorf("Expired() = %v, want %v", got, tt.expired)
+			}
+			if got := Persistent(c, now); got != tt.persistent {
+				t.Errorf("Persistent() = %v, want %v", got, tt.persistent)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []*pb.Cookie
+		secure   bool
+		set      []*pb.Cookie
+		stored   int
+		rejected int
+		want     []string
+	}{
+		{
+			name:   "adds new cookie",
+			set:    []*pb.Cookie{{Key: "a"}},
+			stored: 1,
+			want:   []string{"a"},
+		},
+		{
+			name:     "keeps existing cookies when adding",
+			existing: []*pb.Cookie{{Key: "a", Server: "origin"}},
+			set:      []*pb.Cookie{{Key: "b"}},
+			stored:   1,
+			want:     []string{"a", "b"},
+		},
+		{
+			name:     "several cookies in one response",
+			existing: []*pb.Cookie{{Key: "a", Server: "origin"}},
+			set:      []*pb.Cookie{{Key: "b"}, {Key: "c"}},
+			stored:   2,
+			want:     []string{"a", "b", "c"},
+		},
+		{
+			name:     "overwrites same key",
+			existing: []*pb.Cookie{{Key: "a", Server: "origin", Value: "old"}},
+			set:      []*pb.Cookie{{Key: "a", Value: "new"}},
+			stored:   1,
+			want:     []string{"a"},
+		},
+		{
+			name:     "same key different page is a different cookie",
+			existing: []*pb.Cookie{{Key: "a", Server: "origin", Page: "one"}},
+			set:      []*pb.Cookie{{Key: "a", Page: "two"}},
+			stored:   1,
+			want:     []string{"a", "a"},
+		},
+		{
+			name:     "rejects blank key",
+			set:      []*pb.Cookie{{Key: ""}},
+			rejected: 1,
+			want:     []string{},
+		},
+		{
+			name:     "rejects secure cookie over insecure connection",
+			set:      []*pb.Cookie{{Key: "a", Secure: true}},
+			rejected: 1,
+			want:     []string{},
+		},
+		{
+			name:   "accepts secure cookie over secure connection",
+			secure: true,
+			set:    []*pb.Cookie{{Key: "a", Secure: true}},
+			stored: 1,
+			want:   []string{"a"},
+		},
+		{
+			name:     "rejects samesite none without secure",
+			secure:   true,
+			set:      []*pb.Cookie{{Key: "a", SameSite: pb.Cookie_NONE}},
+			rejected: 1,
+			want:     []string{},
+		},
+		{
+			name:     "past expires deletes",
+			existing: []*pb.Cookie{{Key: "a", Server: "origin"}, {Key: "b", Server: "origin"}},
+			set:      []*pb.Cookie{{Key: "a", Expires: past()}},
+			want:     []string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := New()
+			j.Load("origin", tt.existing)
+			stored, rejected := j.Set("origin", tt.secure, tt.set, now)
+			if stored != tt.stored || rejected != tt.rejected {
+				t.Errorf("Set() = %d, %d, want %d, %d",
+					stored, rejected, tt.stored, tt.rejected)
+			}
+			if got := keys(j.All()["origin"]); !sameKeys(got, tt.want) {
+				t.Errorf("jar holds %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	j := New()
+	c := &pb.Cookie{Key: "a"}
+	j.Set("origin", false, []*pb.Cookie{c}, now)
+	if c.Server != "" {
+		t.Errorf("Set() changed the caller's cookie, Server = %q", c.Server)
+	}
+	stored := j.All()["origin"]
+	if len(stored) != 1 || stored[0].Server != "origin" {
+		t.Fatalf("stored cookie should default to the origin, got %v", stored)
+	}
+	if stored[0] == c {
+		t.Errorf("Set() stored the caller's cookie instead of a copy")
+	}
+}
+
+func TestLoadCopies(t *testing.T) {
+	j := New()
+	cookies := []*pb.Cookie{{Key: "a", Server: "origin"}, nil}
+	j.Load("origin", cookies)
+	cookies[0].Value = "changed"
+	cookies[0] = &pb.Cookie{Key: "b", Server: "origin"}
+	stored := j.All()["origin"]
+	if len(stored) != 1 || stored[0].Key != "a" || stored[0].Value != "" {
+		t.Errorf("Load() kept the caller's cookies, got %v", stored)
+	}
+}
+
+func TestCookies(t *testing.T) {
+	secureReq := Request{Server: "origin", Page: "home", Secure: true}
+	tests := []struct {
+		name   string
+		origin string
+		cookie *pb.Cookie
+		req    Request
+		send   bool
+	}{
+		{"plain cookie", "origin",
+			&pb.Cookie{Key: "a", Server: "origin"},
+			Request{Server: "origin"}, true},
+		{"other server", "origin",
+			&pb.Cookie{Key: "a", Server: "origin"},
+			Request{Server: "elsewhere"}, false},
+		{"private", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", Private: true},
+			secureReq, false},
+		{"expired", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", Expires: past()},
+			secureReq, false},
+		{"not yet expired", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", Expires: future()},
+			secureReq, true},
+		{"secure over insecure", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", Secure: true},
+			Request{Server: "origin"}, false},
+		{"secure over secure", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", Secure: true},
+			secureReq, true},
+		{"page matches", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", Page: "home"},
+			secureReq, true},
+		{"page differs", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", Page: "other"},
+			secureReq, false},
+		{"strict same-site", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", SameSite: pb.Cookie_STRICT},
+			Request{Server: "origin", Initiator: "origin"}, true},
+		{"strict user initiated", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", SameSite: pb.Cookie_STRICT},
+			Request{Server: "origin"}, true},
+		{"strict cross-site", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", SameSite: pb.Cookie_STRICT},
+			Request{Server: "origin", Initiator: "elsewhere"}, false},
+		{"none cross-site secure", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", SameSite: pb.Cookie_NONE, Secure: true},
+			Request{Server: "origin", Initiator: "elsewhere", Secure: true}, true},
+		{"none over insecure", "origin",
+			&pb.Cookie{Key: "a", Server: "origin", SameSite: pb.Cookie_NONE},
+			Request{Server: "origin"}, false},
+		{"third party none secure", "tracker",
+			&pb.Cookie{Key: "a", Server: "origin", SameSite: pb.Cookie_NONE, Secure: true},
+			secureReq, true},
+		{"third party strict", "tracker",
+			&pb.Cookie{Key: "a", Server: "origin", SameSite: pb.Cookie_STRICT},
+			Request{Server: "origin"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := New()
+			j.Load(tt.origin, []*pb.Cookie{tt.cookie})
+			req := tt.req
+			got := len(j.Cookies(&req, now)) == 1
+			if got != tt.send {
+				t.Errorf("sent = %v, want %v", got, tt.send)
+			}
+		})
+	}
+}
+
+func TestCookiesOrder(t *testing.T) {
+	j := New()
+	j.Load("b", []*pb.Cookie{{Key: "b1", Server: "dest", SameSite: pb.Cookie_NONE, Secure: true}})
+	j.Load("dest", []*pb.Cookie{{Key: "d1", Server: "dest"}, {Key: "d2", Server: "dest"}})
+	j.Load("a", []*pb.Cookie{{Key: "a1", Server: "dest", SameSite: pb.Cookie_NONE, Secure: true}})
+	got := keys(j.Cookies(&Request{Server: "dest", Secure: true}, now))
+	want := []string{"a1", "b1", "d1", "d2"}
+	if !sameKeys(got, want) {
+		t.Errorf("Cookies() = %v, want %v", got, want)
+	}
+}
+
+func TestPersistentJar(t *testing.T) {
+	j := New()
+	j.Load("origin", []*pb.Cookie{
+		{Key: "session"},
+		{Key: "kept", Expires: future()},
+		{Key: "gone", Expires: past()},
+	})
+	j.Load("sessiononly", []*pb.Cookie{{Key: "session"}})
+	p := j.Persistent(now)
+	if len(p) != 1 || !sameKeys(keys(p["origin"]), []string{"kept"}) {
+		t.Errorf("Persistent() = %v", p)
+	}
+	if j.Count() != 4 {
+		t.Errorf("Persistent() should not modify the jar, Count() = %d", j.Count())
+	}
+}
+
+func TestDeleteAndClear(t *testing.T) {
+	j := New()
+	j.Load("a", []*pb.Cookie{{Key: "a1"}, {Key: "a2"}, {Key: "a3"}})
+	j.Load("b", []*pb.Cookie{{Key: "b1"}})
+	snapshot := j.All()
+	if c, ok := j.Delete("a", 1); !ok || c.Key != "a2" {
+		t.Fatalf("Delete() = %v, %v", c, ok)
+	}
+	if !sameKeys(keys(snapshot["a"]), []string{"a1", "a2", "a3"}) {
+		t.Errorf("Delete() changed an earlier All() copy: %v", keys(snapshot["a"]))
+	}
+	if _, ok := j.Delete("a", 5); ok {
+		t.Errorf("Delete() out of range should fail")
+	}
+	if _, ok := j.Delete("b", 0); !ok {
+		t.Errorf("Delete() of last cookie failed")
+	}
+	if !sameKeys(j.Servers(), []string{"a"}) {
+		t.Errorf("server with no cookies left should be dropped, got %v", j.Servers())
+	}
+	j.ClearServer("a")
+	if j.Count() != 0 {
+		t.Errorf("ClearServer() left %d cookies", j.Count())
+	}
+	j.Load("c", []*pb.Cookie{{Key: "c1"}})
+	j.Clear()
+	if j.Count() != 0 || len(j.Servers()) != 0 {
+		t.Errorf("Clear() left cookies behind")
+	}
+}
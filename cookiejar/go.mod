@@ -0,0 +1,5 @@
+module github.com/rendicott/uggly-client/cookiejar
+
+go 1.15
+
+require github.com/rendicott/uggly v0.1.2
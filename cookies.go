@@ -5,13 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	pb "github.com/rendicott/uggly"
+	"github.com/rendicott/uggly-client/cookiejar"
 	"github.com/rendicott/uggsec"
 	"google.golang.org/grpc/metadata"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 func (b *ugglyBrowser) loadCookies() (err error) {
-	contents, err := b.readVault()
+	contents, err := b.readVault(*b.settings.VaultFile)
 	if err != nil {
 		return err
 	}
@@ -21,9 +27,9 @@ func (b *ugglyBrowser) loadCookies() (err error) {
 		return err
 	}
 	for _, jarCookie := range cookieJar {
-		b.cookies[jarCookie.Server] = jarCookie.Cookies
+		b.cookies.Load(jarCookie.Server, jarCookie.Cookies)
 	}
-	loggo.Info("successfully loaded cookies from vault", "num_cookies", len(b.cookies))
+	loggo.Info("successfully loaded cookies from vault", "num_cookies", b.cookies.Count())
 	return err
 }
 
@@ -32,11 +38,19 @@ type serverCookies struct {
 	Cookies []*pb.Cookie
 }
 
-func (b *ugglyBrowser) loadVault() (vault *uggsec.Vault, err error) {
+// the OS keyring entry holding the vault key
+const (
+	vaultService = "ugglyc"
+	vaultUser    = "browser"
+)
+
+// loadVault opens the encrypted file with the browser's vault key.
+// The cookie jar and saved logins are separate files sharing the key.
+func (b *ugglyBrowser) loadVault(filename string) (vault *uggsec.Vault, err error) {
 	params := uggsec.VaultInput{
-		Filename: *b.settings.VaultFile,
-		Service:  "ugglyc",
-		User:     "browser",
+		Filename: filename,
+		Service:  vaultService,
+		User:     vaultUser,
 	}
 	vault, err = uggsec.InitSmart(&params)
 	if err != nil {
@@ -52,78 +66,46 @@ func (b *ugglyBrowser) loadVault() (vault *uggsec.Vault, err error) {
 	return vault, err
 }
 
-func (b *ugglyBrowser) writeVault(contents string) (err error) {
-	vault, err := b.loadVault()
+// writeVault encrypts contents to a temporary file next to filename and
+// renames it over the original so a crash or kill in the middle of a
+// write never leaves a half written vault behind
+func (b *ugglyBrowser) writeVault(filename, contents string) (err error) {
+	tmp := filename + ".tmp"
+	vault, err := b.loadVault(tmp)
+	if err != nil {
+		return err
+	}
+	err = vault.Write(contents)
 	if err != nil {
+		os.Remove(tmp)
 		return err
 	}
-	return vault.Write(contents)
+	return os.Rename(tmp, filename)
 }
 
-func (b *ugglyBrowser) readVault() (contents string, err error) {
-	vault, err := b.loadVault()
+func (b *ugglyBrowser) readVault(filename string) (contents string, err error) {
+	vault, err := b.loadVault(filename)
 	if err != nil {
 		return contents, err
 	}
 	return vault.Read()
 }
 
-func (b *ugglyBrowser) countCookies() (cookieCount int) {
-	for _, cookies := range b.cookies {
-		cookieCount += len(cookies)
-	}
-	return cookieCount
-}
-
-func (b *ugglyBrowser) clearSessionCookies() {
-	loggo.Debug("before clearing session cookies", "num_cookies", b.countCookies())
-	now := time.Now()
-	newCookieMap := make(map[string][]*pb.Cookie, 0)
-	for server, cookies := range b.cookies {
-		loggo.Debug("clearSessionCookies(), processing cookies for server",
-			"server", server)
-		storeCookies := []*pb.Cookie{}
-		for _, cookie := range cookies {
-			loggo.Debug("checking cookie", "cookie.Key", cookie.Key)
-			// check expiration
-			if cookie.Expires == "" {
-				loggo.Debug("throwing out cookie because Expired is blank",
-					"cookie.Key", cookie.Key)
-				// means it's a session cookie and will be thrown out
-				continue
-			} else if cookie.Expires != "" {
-				expires, err := time.Parse(time.RFC1123, cookie.Expires)
-				if err != nil {
-					loggo.Debug("throwing out cookie because can't parse Expired",
-						"cookie.Key", cookie.Key)
-					// can't tell so we throw out anyway
-					continue
-				}
-				if now.After(expires) {
-					loggo.Debug("throwing out cookie because Expired",
-						"cookie.Key", cookie.Key)
-					continue
-				} else {
-					loggo.Debug("adding cookie to storeCookies",
-						"cookie.Key", cookie.Key)
-					storeCookies = append(storeCookies, cookie)
-				}
-
-			}
-		}
-		if len(storeCookies) > 0 {
-			newCookieMap[server] = storeCookies
-		}
-	}
-	b.cookies = newCookieMap
-	loggo.Debug("after clearing session cookies", "num_cookies", b.countCookies())
-}
-
+// storeCookies writes the persistent cookies to the vault unless they
+// are unchanged since the last write. It's called in the background by
+// cookieSaver and once more on exit.
 func (b *ugglyBrowser) storeCookies() (err error) {
-	// clear non permanent cookies before storing
-	b.clearSessionCookies()
+	b.cookieSaveMu.Lock()
+	defer b.cookieSaveMu.Unlock()
+	// only permanent cookies are stored, session cookies die with us
 	cookieJar := []*serverCookies{}
-	for server, cookies := range b.cookies {
+	for server, cookies := range b.cookies.Persistent(time.Now()) {
+		policy := b.settings.serverCookiePolicy(server)
+		if policy == cookiePolicySession || policy == cookiePolicyBlock {
+			loggo.Debug("not jarring cookies due to policy",
+				"server", server, "policy", policy)
+			continue
+		}
 		cookieJar = append(cookieJar, &serverCookies{
 			Server:  server,
 			Cookies: cookies,
@@ -132,96 +114,236 @@ func (b *ugglyBrowser) storeCookies() (err error) {
 		loggo.Debug(msg)
 	}
 	loggo.Info("jarred cookies for servers", "num_servers", len(cookieJar))
+	// stable order so unchanged jars compare equal
+	sort.Slice(cookieJar, func(i, j int) bool {
+		return cookieJar[i].Server < cookieJar[j].Server
+	})
 	dat, err := json.Marshal(cookieJar)
 	if err != nil {
 		return err
 	}
+	if string(dat) == b.savedCookies {
+		loggo.Debug("cookies unchanged since last save, skipping write")
+		return nil
+	}
 	//err = ioutil.WriteFile("unencrypted-cookies.json", dat, 0755)
 	//if err != nil {
 	//	return err
 	//}
-	err = b.writeVault(string(dat))
+	err = b.writeVault(*b.settings.VaultFile, string(dat))
 	if err == nil {
+		b.savedCookies = string(dat)
 		loggo.Info("successfully stored cookies to disk")
 	}
 	return err
 }
 
-// takes a PageRequest and integrates any eligible cookies from the browser's cache
-// into the request and returns the cookie'fied request as a response
-func (b *ugglyBrowser) addCookies(
-	ctx context.Context, pq *pb.PageRequest) (context.Context, *pb.PageRequest) {
-	destServer := pq.Server
-	crossSiteCookies := []*pb.Cookie{}
-	// find any cookies set by other servers that may need to be sent to this destination
-	for serverCache, serverCookies := range b.cookies {
-		for _, cookie := range serverCookies {
-			// make sure we're looking at true cross site cookies
-			if cookie.Server == destServer && serverCache != destServer {
-				// check to see if we're allowed to send
-				// SameSite 0 = Strict
-				// SameSite 1 = None
-				if cookie.SameSite == 1 && cookie.Secure {
-					crossSiteCookies = append(crossSiteCookies, cookie)
-				} else {
-					loggo.Debug("cookie not allowed to be sent cross site")
-				}
-			}
+// saveCookiesSoon asks cookieSaver to store the jar without waiting.
+// A save that's already queued covers this request too.
+func (b *ugglyBrowser) saveCookiesSoon() {
+	select {
+	case b.cookieSaves <- struct{}{}:
+	default:
+	}
+}
+
+// cookieSaver stores the cookie jar in the background whenever cookies
+// change so a crash doesn't lose the session's persistent cookies.
+// Requests arriving within cookieSaveDelay are coalesced into one write.
+func (b *ugglyBrowser) cookieSaver() {
+	for range b.cookieSaves {
+		time.Sleep(b.cookieSaveDelay)
+		// anything requested while we waited is covered by this write
+		select {
+		case <-b.cookieSaves:
+		default:
+		}
+		err := b.storeCookies()
+		if err != nil {
+			loggo.Error("error storing cookies in background", "error", err.Error())
 		}
 	}
-	var allPotentialCookies []*pb.Cookie
-	allPotentialCookies = append(allPotentialCookies, b.cookies[destServer]...)
-	allPotentialCookies = append(allPotentialCookies, crossSiteCookies...)
-	loggo.Info("processing potential cookies", "potentialCookies", len(allPotentialCookies))
-	finalCookies := []*pb.Cookie{}
-	// now we go through this server's cached cookies combined with cross site and process further
-	now := time.Now()
-	for _, potentialCookie := range allPotentialCookies {
-		if potentialCookie.Private {
-			loggo.Debug("discarding cookie because it's marked private")
+}
+
+// exportCookies writes the persistent cookies of the given servers,
+// or every server if none are given, to filename as plain JSON in the
+// same format as the vault so they can be imported on another machine.
+// Returns the number of cookies written.
+func (b *ugglyBrowser) exportCookies(filename string, servers []string) (count int, err error) {
+	cookieJar := []*serverCookies{}
+	for server, cookies := range b.cookies.Persistent(time.Now()) {
+		if len(servers) > 0 && !containsString(servers, server) {
 			continue
 		}
-		// check if cookie is expired
-		expires, err := time.Parse(time.RFC1123, potentialCookie.Expires)
-		// if this errors then we just assume it's a session cookie since
-		// we can't validate if it's expired or not. Therefore it has no
-		// bearing on the logic we would use to exclude it based on expiry
-		if err == nil {
-			// means we can actually compare against our local date and exclude if expired
-			if now.After(expires) {
-				loggo.Debug("discarding cookie because it's expired")
-				continue
-			}
-		}
-		if potentialCookie.SameSite == pb.Cookie_STRICT && potentialCookie.Server != destServer {
-			// means this server is not the server that set the cookie
-			// and since sameSite is Strict then we need to discard
-			loggo.Debug("discarding cookie because samesite set to strict")
+		cookieJar = append(cookieJar, &serverCookies{
+			Server:  server,
+			Cookies: cookies,
+		})
+		count += len(cookies)
+	}
+	sort.Slice(cookieJar, func(i, j int) bool {
+		return cookieJar[i].Server < cookieJar[j].Server
+	})
+	dat, err := json.MarshalIndent(cookieJar, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	// the file is not encrypted so keep it away from other users
+	err = ioutil.WriteFile(filename, dat, 0600)
+	if err != nil {
+		return 0, err
+	}
+	loggo.Info("exported cookies", "file", filename, "num_cookies", count)
+	return count, err
+}
+
+// importCookies reads a file written by exportCookies and replaces the
+// cookies of every server in it, limited to servers if any are given.
+// The jar is stored so the cookies are encrypted with this machine's
+// vault right away. Returns the number of cookies imported and skipped.
+func (b *ugglyBrowser) importCookies(filename string, servers []string) (count, skipped int, err error) {
+	dat, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	cookieJar := []*serverCookies{}
+	err = json.Unmarshal(dat, &cookieJar)
+	if err != nil {
+		return 0, 0, fmt.Errorf("'%s' is not a cookie export: %s", filename, err.Error())
+	}
+	count, skipped = b.importJar(cookieJar, servers)
+	loggo.Info("imported cookies", "file", filename,
+		"num_cookies", count, "skipped_cookies", skipped)
+	return count, skipped, b.storeCookies()
+}
+
+// importJar puts imported cookies in the jar the way a server setting
+// them would. Servers whose policy keeps cookies off disk are skipped
+// since the import is only there to store them, and the jar's Set rules
+// drop cookies a server couldn't have set. Asking for the import is the
+// user's answer to a prompt policy.
+func (b *ugglyBrowser) importJar(cookieJar []*serverCookies, servers []string) (count, skipped int) {
+	now := time.Now()
+	for _, jarCookie := range cookieJar {
+		if len(servers) > 0 && !containsString(servers, jarCookie.Server) {
 			continue
 		}
-		if potentialCookie.SameSite == pb.Cookie_NONE && !pq.Secure {
-			// means cookie's samesite property is set to Lax but since
-			// the request is not secure we can't send the cookie
-			loggo.Debug("discarding cookie as samesite == lax and request is not secure")
+		b.settingsMu.Lock()
+		policy := b.settings.serverCookiePolicy(jarCookie.Server)
+		b.settingsMu.Unlock()
+		if policy == cookiePolicySession || policy == cookiePolicyBlock {
+			loggo.Info("not importing cookies due to policy",
+				"server", jarCookie.Server, "policy", policy)
+			skipped += len(jarCookie.Cookies)
 			continue
 		}
-		if potentialCookie.Page != "" {
-			if potentialCookie.Page != pq.Name {
-				// means the page restriction was set in the cookie and the
-				// page we're requesting is not a match
-				loggo.Debug("discarding cookie as requested page != cookie page")
-				continue
-			}
+		b.cookies.ClearServer(jarCookie.Server)
+		// they were set over whatever connection the server used, the
+		// export doesn't say so secure cookies are taken at their word
+		// rejected and expired cookies count as skipped
+		stored, _ := b.cookies.Set(jarCookie.Server, true, jarCookie.Cookies, now)
+		count += stored
+		skipped += len(jarCookie.Cookies) - stored
+	}
+	return count, skipped
+}
+
+// cookieTransfer runs the -cookies-export and -cookies-import modes
+// against the vault without starting the browser. Returns the exit code.
+func (b *ugglyBrowser) cookieTransfer(exportFile, importFile, servers string) int {
+	err := b.loadCookies()
+	if _, statErr := os.Stat(*b.settings.VaultFile); os.IsNotExist(statErr) && exportFile == "" {
+		// importing into a fresh vault is fine
+		err = nil
+	}
+	if err != nil {
+		// don't let an import overwrite a vault we couldn't read
+		fmt.Fprintf(os.Stderr, "error reading cookie vault '%s': %s\n",
+			*b.settings.VaultFile, err.Error())
+		return 1
+	}
+	if exportFile != "" {
+		count, err := b.exportCookies(exportFile, splitList(servers))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error exporting cookies: %s\n", err.Error())
+			return 1
 		}
-		// if it passed the gauntlet then it goes into final cookies
-		finalCookies = append(finalCookies, potentialCookie)
+		fmt.Printf("exported %d cookies to '%s'. The file is NOT encrypted, "+
+			"delete it once it has been imported.\n", count, exportFile)
 	}
-	// now prep the final cookies for sending and append them to pagerequest
-	for _, cookie := range finalCookies {
-		if cookie.Secure && !pq.Secure {
-			loggo.Debug("discarding secure cookie on unsecure connection")
-			continue
+	if importFile != "" {
+		count, skipped, err := b.importCookies(importFile, splitList(servers))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error importing cookies: %s\n", err.Error())
+			return 1
+		}
+		fmt.Printf("imported %d cookies into '%s', skipped %d\n",
+			count, *b.settings.VaultFile, skipped)
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// cookieTransferProcess handles the export and import forms on the
+// cookie manager page
+func (b *ugglyBrowser) cookieTransferProcess(export bool, formContents map[string]string) {
+	filename := strings.TrimSpace(formContents["CookieFile"])
+	servers := splitList(formContents["CookieServers"])
+	var infoMsg string
+	if filename == "" {
+		infoMsg = "a file name is required"
+	} else if export {
+		count, err := b.exportCookies(filename, servers)
+		if err != nil {
+			loggo.Error("error exporting cookies", "err", err.Error())
+			infoMsg = fmt.Sprintf("error exporting cookies to '%s', check log", filename)
+		} else {
+			infoMsg = fmt.Sprintf("exported %d cookies to '%s' (unencrypted)", count, filename)
 		}
+	} else {
+		count, skipped, err := b.importCookies(filename, servers)
+		if err != nil {
+			loggo.Error("error importing cookies", "err", err.Error())
+			infoMsg = fmt.Sprintf("error importing cookies from '%s', check log", filename)
+		} else {
+			infoMsg = fmt.Sprintf("imported %d cookies from '%s', skipped %d (see log)",
+				count, filename, skipped)
+		}
+	}
+	b.sendMessage(infoMsg, "cookie-transfer")
+	b.cookiesPage(infoMsg)
+}
+
+// pageInitiator returns the server of the page being shown, which is the
+// initiator of any navigation that page starts. Local pages are the
+// browser's own so they don't count.
+func (b *ugglyBrowser) pageInitiator() string {
+	if b.currentPageLocal != nil {
+		return ""
+	}
+	return b.sess.server
+}
+
+// takes a PageRequest and integrates any eligible cookies from the browser's cache
+// into the request and returns the cookie'fied request as a response. A blank
+// initiator means the user asked for the page directly.
+func (b *ugglyBrowser) addCookies(ctx context.Context, pq *pb.PageRequest,
+	initiator string) (context.Context, *pb.PageRequest) {
+	req := cookiejar.Request{
+		Server:    pq.Server,
+		Page:      pq.Name,
+		Secure:    pq.Secure,
+		Initiator: initiator,
+	}
+	for _, cookie := range b.cookies.Cookies(&req, time.Now()) {
 		// check to see if we need to put the cookie in metadata
 		if cookie.Metadata {
 			// inject metadata into ctx
@@ -232,58 +354,149 @@ func (b *ugglyBrowser) addCookies(
 	}
 	loggo.Info("finished processing sendCookies",
 		"request.sendCookies", len(pq.SendCookies),
-		"potentialCookies", len(allPotentialCookies),
-		"discarded_cookies", len(allPotentialCookies)-len(pq.SendCookies))
+		"initiator", req.Initiator)
 	return ctx, pq
 }
 
-// cookieExists searches through the browser's cookie cache for a given server and
-// returns true if a cookie with the same Key already exists
-func (b *ugglyBrowser) cookieExists(server string, cookie *pb.Cookie) bool {
-	if existingCookies, ok := b.cookies[server]; ok {
-		for _, ecook := range existingCookies {
-			if ecook.Key == cookie.Key {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 // setCookies processes the cookies coming back from a PageResponse and sets them in the browser's
-// cookie cache. It overwrites any existing cookies for the same server with the same Key
+// cookie jar which overwrites any existing cookies for the same server with the same Key and Page
 func (b *ugglyBrowser) setCookies(pr *pb.PageResponse) {
 	// since we store cookies under server keys for security
 	server := b.sess.server
-	novelCount := 0
-	for _, rawCookie := range pr.SetCookies {
-		// first, let's be nice and set server if none is specified
-		// which can make it easier on server devs
-		if rawCookie.Server == "" {
-			rawCookie.Server = server
-		}
-		if existingCookies, ok := b.cookies[server]; ok {
-			if b.cookieExists(server, rawCookie) {
-				for i, ecook := range existingCookies {
-					if ecook.Key == rawCookie.Key {
-						novelCount++
-						loggo.Debug("overwriting existing cookie",
-							"rawCookie.Key", rawCookie.Key)
-						existingCookies[i] = rawCookie // overwrite existing
-					}
-				}
-			} else {
-				novelCount++
-				loggo.Debug("adding new cookie", "rawCookie.Key", rawCookie.Key)
-				existingCookies = append(existingCookies, rawCookie)
-			}
-			b.cookies[server] = existingCookies
-		} else {
-			b.cookies[server] = pr.SetCookies
+	// policies are per server:port like the other per server settings
+	site := fmt.Sprintf("%s:%s", b.sess.server, b.sess.port)
+	if len(pr.SetCookies) > 0 {
+		switch b.settings.cookiePolicy(site) {
+		case cookiePolicyBlock:
+			loggo.Info("blocked cookies from server due to policy",
+				"site", site, "num_cookies", len(pr.SetCookies))
+			return
+		case cookiePolicyPrompt:
+			b.holdCookies(site, server, b.sess.secure, pr.SetCookies)
+			return
 		}
 	}
+	stored, rejected := b.cookies.Set(server, b.sess.secure, pr.SetCookies, time.Now())
 	loggo.Info("set cookies from server",
-		"total-current-server-cookies", len(b.cookies[server]),
-		"novel-cookies-added", novelCount,
+		"total-cookies", b.cookies.Count(),
+		"novel-cookies-added", stored,
+		"rejected-cookies", rejected,
 	)
+	if len(pr.SetCookies) > 0 {
+		b.saveCookiesSoon()
+	}
+}
+
+// heldCookies are cookies waiting on the user to pick a policy along
+// with the server they're stored under and whether the connection they
+// came over was secure
+type heldCookies struct {
+	server  string
+	secure  bool
+	cookies []*pb.Cookie
+}
+
+// holdCookies parks cookies from a server:port that has no policy yet
+// while the browser is in prompt mode. The user decides in the cookie
+// manager.
+func (b *ugglyBrowser) holdCookies(site, server string, secure bool, setCookies []*pb.Cookie) {
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+	held, seen := b.pendingCookies[site]
+	if !seen {
+		held = &heldCookies{server: server}
+		b.pendingCookies[site] = held
+	}
+	held.secure = secure
+	held.cookies = append(held.cookies, setCookies...)
+	loggo.Info("holding cookies until user sets a policy",
+		"site", site, "num_cookies", len(held.cookies))
+	if !seen {
+		go b.sendMessage(fmt.Sprintf(
+			"'%s' wants to set cookies, choose a policy in the Cookie Manager (F9)",
+			site), "cookie-prompt")
+	}
+}
+
+// resolvePending stores the user's policy choice for a server:port that
+// was waiting in prompt mode and accepts or drops its held cookies
+func (b *ugglyBrowser) resolvePending(site, policy string) string {
+	b.pendingMu.Lock()
+	held := b.pendingCookies[site]
+	delete(b.pendingCookies, site)
+	b.pendingMu.Unlock()
+	b.settingsMu.Lock()
+	b.settings.setCookiePolicy(site, policy)
+	b.settingsMu.Unlock()
+	infoMsg := fmt.Sprintf("set cookie policy '%s' for '%s'", policy, site)
+	if policy != cookiePolicyBlock && held != nil {
+		b.cookies.Set(held.server, held.secure, held.cookies, time.Now())
+		b.saveCookiesSoon()
+	}
+	if err := b.settingsSave(); err != nil {
+		infoMsg += ", error saving settings to disk"
+	}
+	return infoMsg
+}
+
+func (b *ugglyBrowser) cookiesPage(infoMsg string) {
+	thisfunc := "cookiesPage"
+	loggo.Info("building cookie manager page")
+	// remember the order so links from the page can be resolved
+	b.cookiePageServers = b.cookies.Servers()
+	b.cookiePagePending = []string{}
+	b.pendingMu.Lock()
+	for site := range b.pendingCookies {
+		b.cookiePagePending = append(b.cookiePagePending, site)
+	}
+	b.pendingMu.Unlock()
+	sort.Strings(b.cookiePagePending)
+	b.currentPage = buildCookies(b.vW, b.vH, b.cookiePageServers, b.cookies.All(),
+		b.cookiePagePending, b.settings, infoMsg)
+	b.currentPageLocal = b.currentPage
+	go b.sendMessage("Cookie Manager", thisfunc)
+	b.handle(b.buildDraw(thisfunc))
+}
+
+// cookieLinkRouter handles the delete, clear and policy links from the
+// cookie manager page. Indexes refer to b.cookiePageServers (or
+// b.cookiePagePending for policies) and the order of that server's
+// cookies when the page was built.
+func (b *ugglyBrowser) cookieLinkRouter(pageName string) {
+	chunks := strings.Split(pageName, "_")
+	infoMsg := "cookie not found"
+	serverAt := func(s string) (string, bool) {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 || i >= len(b.cookiePageServers) {
+			return "", false
+		}
+		return b.cookiePageServers[i], true
+	}
+	switch {
+	case strings.Contains(pageName, "cookie_policy") && len(chunks) > 3:
+		i, err := strconv.Atoi(chunks[2])
+		if err == nil && i >= 0 && i < len(b.cookiePagePending) && validCookiePolicy(chunks[3]) {
+			infoMsg = b.resolvePending(b.cookiePagePending[i], chunks[3])
+		}
+	case strings.Contains(pageName, "cookie_clearall"):
+		b.cookies.Clear()
+		infoMsg = "cleared all cookies"
+	case strings.Contains(pageName, "cookie_clearserver") && len(chunks) > 2:
+		if server, ok := serverAt(chunks[2]); ok {
+			b.cookies.ClearServer(server)
+			infoMsg = fmt.Sprintf("cleared cookies for '%s'", server)
+		}
+	case strings.Contains(pageName, "cookie_delete") && len(chunks) > 3:
+		server, ok := serverAt(chunks[2])
+		index, err := strconv.Atoi(chunks[3])
+		if ok && err == nil {
+			if cookie, ok := b.cookies.Delete(server, index); ok {
+				infoMsg = fmt.Sprintf("deleted cookie '%s' for '%s'", cookie.Key, server)
+			}
+		}
+	}
+	loggo.Info("cookie manager action", "action", pageName, "result", infoMsg)
+	b.saveCookiesSoon()
+	b.sendMessage(infoMsg, "cookie-manager")
+	b.cookiesPage(infoMsg)
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	pb "github.com/rendicott/uggly"
+	"github.com/rendicott/uggly-client/cookiejar"
+	"testing"
+	"time"
+)
+
+func TestImportJar(t *testing.T) {
+	future := time.Now().Add(time.Hour).Format(time.RFC1123)
+	past := time.Now().Add(-time.Hour).Format(time.RFC1123)
+	tests := []struct {
+		name        string
+		policies    map[string]string
+		defaultPol  string
+		cookies     []*pb.Cookie
+		servers     []string
+		wantCount   int
+		wantSkipped int
+		wantInJar   int // a skipped server keeps its one old cookie
+	}{
+		{"allowed", nil, "", []*pb.Cookie{{Key: "a", Expires: future}}, nil, 1, 0, 1},
+		{"prompt default", nil, cookiePolicyPrompt,
+			[]*pb.Cookie{{Key: "a", Expires: future}}, nil, 1, 0, 1},
+		{"blocked on a port", map[string]string{"server:4443": cookiePolicyBlock}, "",
+			[]*pb.Cookie{{Key: "a", Expires: future}}, nil, 0, 1, 1},
+		{"session only", map[string]string{"server:4443": cookiePolicySession}, "",
+			[]*pb.Cookie{{Key: "a", Expires: future}}, nil, 0, 1, 1},
+		{"other server blocked", map[string]string{"other:4443": cookiePolicyBlock}, "",
+			[]*pb.Cookie{{Key: "a", Expires: future}}, nil, 1, 0, 1},
+		{"no key", nil, "", []*pb.Cookie{{Value: "x", Expires: future}}, nil, 0, 1, 0},
+		{"none without secure", nil, "",
+			[]*pb.Cookie{{Key: "a", Expires: future, SameSite: pb.Cookie_NONE}}, nil, 0, 1, 0},
+		{"secure", nil, "", []*pb.Cookie{{Key: "a", Expires: future, Secure: true}}, nil, 1, 0, 1},
+		{"expired", nil, "", []*pb.Cookie{{Key: "a", Expires: past}}, nil, 0, 1, 0},
+		{"not a listed server", nil, "", []*pb.Cookie{{Key: "a", Expires: future}},
+			[]string{"other"}, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		b := settingsBrowser(t, func(s *ugglyBrowserSettings) {
+			s.CookiePolicies = tt.policies
+			if tt.defaultPol != "" {
+				s.DefaultCookiePolicy = tt.defaultPol
+			}
+		})
+		b.cookies = cookiejar.New()
+		// replaced by the import unless it's skipped
+		b.cookies.Load("server", []*pb.Cookie{{Key: "old", Expires: future}})
+		jar := []*serverCookies{{Server: "server", Cookies: tt.cookies}}
+		count, skipped := b.importJar(jar, tt.servers)
+		if count != tt.wantCount || skipped != tt.wantSkipped {
+			t.Errorf("%s: imported %d, skipped %d, want %d, %d",
+				tt.name, count, skipped, tt.wantCount, tt.wantSkipped)
+		}
+		if got := len(b.cookies.All()["server"]); got != tt.wantInJar {
+			t.Errorf("%s: %d cookies in the jar, want %d", tt.name, got, tt.wantInJar)
+		}
+		if tt.cookies[0].Server != "" {
+			t.Errorf("%s: import changed the file's cookie", tt.name)
+		}
+	}
+}
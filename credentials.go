@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// credentialFileName is stored next to the cookie vault and encrypted
+// with the same key
+const credentialFileName = "credentials.json.encrypted"
+
+// credential is a saved set of form values for a login form
+type credential struct {
+	Server    string            // server:port that served the form
+	Form      string            // form name with any localAuthUuid removed
+	Values    map[string]string // textbox name to contents
+	Passwords []string          // names of the password textboxes
+	Never     bool              // user asked to never save this form
+	Saved     string            // RFC1123 time it was saved
+}
+
+func (b *ugglyBrowser) credentialFile() string {
+	return filepath.Join(filepath.Dir(*b.settings.VaultFile), credentialFileName)
+}
+
+func (b *ugglyBrowser) loadCredentials() (err error) {
+	contents, err := b.readVault(b.credentialFile())
+	if err != nil {
+		return err
+	}
+	creds := []*credential{}
+	err = json.Unmarshal([]byte(contents), &creds)
+	if err != nil {
+		return err
+	}
+	b.credentials = creds
+	loggo.Info("successfully loaded saved logins from vault", "num_logins", len(creds))
+	return err
+}
+
+func (b *ugglyBrowser) storeCredentials() (err error) {
+	dat, err := json.Marshal(b.credentials)
+	if err != nil {
+		return err
+	}
+	err = b.writeVault(b.credentialFile(), string(dat))
+	if err == nil {
+		loggo.Info("successfully stored saved logins to disk")
+	}
+	return err
+}
+
+// findCredential returns the saved login for the server's form if any
+func (b *ugglyBrowser) findCredential(server, formName string) *credential {
+	for _, c := range b.credentials {
+		if c.Server == server && c.Form == formKey(formName) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (b *ugglyBrowser) deleteCredential(index int) bool {
+	if index < 0 || index >= len(b.credentials) {
+		return false
+	}
+	b.credentials = append(b.credentials[:index], b.credentials[index+1:]...)
+	return true
+}
+
+// loginForm returns the server:port that served a page form and the
+// names of its password boxes. Forms without passwords return nil.
+func (b *ugglyBrowser) loginForm(name string) (string, []string) {
+	src, ok := b.lookupFormSource(name)
+	if !ok {
+		return "", nil
+	}
+	var passwords []string
+	for _, tb := range src.form.TextBoxes {
+		if tb.Password {
+			passwords = append(passwords, tb.Name)
+		}
+	}
+	return fmt.Sprintf("%s:%s", src.origin, src.originPort), passwords
+}
+
+// offerSaveCredential asks the user whether the values just submitted
+// in a login form should be saved for next time
+func (b *ugglyBrowser) offerSaveCredential(name string, values map[string]string) {
+	server, passwords := b.loginForm(name)
+	if len(passwords) == 0 {
+		return
+	}
+	existing := b.findCredential(server, name)
+	if existing != nil {
+		if existing.Never || sameValues(existing.Values, values) {
+			return
+		}
+	}
+	msg := fmt.Sprintf("Save the login for form '%s' on %s?\n\n"+
+		"(y) save\n(n) not now\n(x) never for this form",
+		formKey(name), server)
+	answer := b.promptKey("Save login", msg, []rune{'y', 'n', 'x'})
+	if answer != 'y' && answer != 'x' {
+		return
+	}
+	if existing == nil {
+		existing = &credential{Server: server, Form: formKey(name)}
+		b.credentials = append(b.credentials, existing)
+	}
+	existing.Never = answer == 'x'
+	existing.Values = nil
+	existing.Passwords = nil
+	if !existing.Never {
+		existing.Values = values
+		existing.Passwords = passwords
+	}
+	existing.Saved = time.Now().Format(time.RFC1123)
+	err := b.storeCredentials()
+	if err != nil {
+		loggo.Error("error storing saved logins", "err", err.Error())
+		go b.sendMessage("error saving login, check log", "credentials")
+	}
+}
+
+// offerAutofill asks to fill a login form the user is activating with
+// saved values. Returns true if the form was rebuilt with the values.
+func (b *ugglyBrowser) offerAutofill(name string) bool {
+	server, passwords := b.loginForm(name)
+	if len(passwords) == 0 {
+		return false
+	}
+	c := b.findCredential(server, name)
+	if c == nil || c.Never {
+		return false
+	}
+	src, ok := b.lookupFormSource(name)
+	if !ok {
+		return false
+	}
+	declineKey := src.pageKey + formKey(name)
+	if b.autofillDeclined[declineKey] || b.formState.has(src.pageKey, name) {
+		// user already said no or has typed something
+		return false
+	}
+	msg := fmt.Sprintf("A saved login exists for form '%s' on %s.\n\n"+
+		"(y) fill it in\n(n) leave the form empty", formKey(name), server)
+	if b.promptKey("Saved login", msg, []rune{'y', 'n'}) != 'y' {
+		b.autofillDeclined[declineKey] = true
+		return false
+	}
+	b.formState.save(src.pageKey, name, c.Values, src.defaults)
+	b.processPageForms(b.currentPage, false, "autofill")
+	b.drawContent("autofill")
+	return true
+}
+
+func sameValues(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *ugglyBrowser) credentialsPage(infoMsg string) {
+	thisfunc := "credentialsPage"
+	loggo.Info("building saved logins page")
+	b.currentPage = buildCredentials(b.vW, b.vH, b.credentials, infoMsg)
+	b.currentPageLocal = b.currentPage
+	go b.sendMessage("Saved Logins", thisfunc)
+	b.handle(b.buildDraw(thisfunc))
+}
@@ -11,7 +11,7 @@
 # colour=blue
 
 
-grc -c grc.conf tail -f uggcli.log.json | jq -c \
+grc -c grc.conf tail -f "${XDG_CACHE_HOME:-$HOME/.cache}/ugglyc/uggcli.log.json" | jq -c \
 	'del(
 		select(
 			(select(.tags != null) 
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/rendicott/uggly"
+	"google.golang.org/protobuf/proto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pageForm is the behavior shared by ugform.Form and widgets.Form
+// so the browser can hand control to either one the same way
+type pageForm interface {
+	ShiftXY(x, y int)
+	Start()
+	Collect() map[string]string
+	Poll(ctx context.Context, interrupt chan struct{}, submit chan string)
+}
+
+// formSource remembers where a converted ugform.Form came from so
+// the browser can find its way back to the original page and form
+// definition after the ugform.Form has been built
+type formSource struct {
+	pageKey    string
+	origin     string // server that served the page holding the form
+	originPort string
+	form       *pb.Form
+	defaults   map[string]string // DefaultValues as sent by the server
+	shiftX     int
+	shiftY     int
+}
+
+// formState holds whatever the user has typed into the forms of each
+// page so the values can be put back after the page is rebuilt by a
+// refresh or resize. Values are keyed by page, then form, then textbox.
+type formState struct {
+	mu    sync.Mutex
+	pages map[string]map[string]map[string]string
+}
+
+func newFormState() *formState {
+	return &formState{
+		pages: make(map[string]map[string]map[string]string),
+	}
+}
+
+// formKey strips the localAuthUuid from local form names since
+// it is regenerated every time a local page is built
+func formKey(name string) string {
+	if len(localAuthUuid) > 1 {
+		return strings.Replace(name, localAuthUuid, "", 1)
+	}
+	return name
+}
+
+// save records the values that differ from the defaults the server
+// sent. If nothing differs the form is dropped from the store.
+func (fs *formState) save(pageKey, formName string, values, defaults map[string]string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	changed := make(map[string]string)
+	for k, v := range values {
+		if d, ok := defaults[k]; ok && d == v {
+			continue
+		}
+		changed[k] = v
+	}
+	if _, ok := fs.pages[pageKey]; !ok {
+		fs.pages[pageKey] = make(map[string]map[string]string)
+	}
+	key := formKey(formName)
+	if len(changed) == 0 {
+		delete(fs.pages[pageKey], key)
+		return
+	}
+	fs.pages[pageKey][key] = changed
+	loggo.Debug("saved in-progress form values",
+		"pageKey", pageKey, "form", key, "fields", len(changed))
+}
+
+// apply returns a copy of the form with the DefaultValue of any textbox
+// the user had already typed into replaced so the rebuilt form shows
+// their input again. The page's own form is never changed since it's
+// where the server's defaults are read from the next time it's built.
+func (fs *formState) apply(pageKey string, form *pb.Form) *pb.Form {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	values, ok := fs.pages[pageKey][formKey(form.Name)]
+	if !ok {
+		return form
+	}
+	form = proto.Clone(form).(*pb.Form)
+	for _, tb := range form.TextBoxes {
+		if v, ok := values[tb.Name]; ok {
+			tb.DefaultValue = v
+		}
+	}
+	loggo.Debug("restored in-progress form values",
+		"pageKey", pageKey, "form", form.Name)
+	return form
+}
+
+// snapshot returns a copy of the values stored for every form on a page
+func (fs *formState) snapshot(pageKey string) map[string]map[string]string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	forms := make(map[string]map[string]string)
+	for form, values := range fs.pages[pageKey] {
+		forms[form] = make(map[string]string)
+		for k, v := range values {
+			forms[form][k] = v
+		}
+	}
+	return forms
+}
+
+// restore puts back values taken by snapshot, e.g., from a saved session
+func (fs *formState) restore(pageKey string, forms map[string]map[string]string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if len(forms) == 0 {
+		return
+	}
+	fs.pages[pageKey] = forms
+}
+
+// has returns true if values are stored for the page's form
+func (fs *formState) has(pageKey, formName string) bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	_, ok := fs.pages[pageKey][formKey(formName)]
+	return ok
+}
+
+// clear drops stored values for a form, e.g., after it was submitted
+func (fs *formState) clear(pageKey, formName string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.pages[pageKey], formKey(formName))
+}
+
+// pageKey returns a string identifying the page currently being
+// displayed. Local pages use their name, everything else the UGRI.
+func (b *ugglyBrowser) pageKey() string {
+	if b.currentPageLocal != nil {
+		return b.currentPageLocal.Name
+	}
+	return *b.sess.genUgri()
+}
+
+// newFormSource captures the server's defaults for a page form. Stored
+// values are only ever applied to a copy so the form still holds them
+// however many times the page is rebuilt.
+func (b *ugglyBrowser) newFormSource(form *pb.Form) *formSource {
+	src := formSource{
+		pageKey:    b.pageKey(),
+		origin:     b.sess.server,
+		originPort: b.sess.port,
+		form:       form,
+		defaults:   make(map[string]string),
+	}
+	for _, tb := range form.TextBoxes {
+		src.defaults[tb.Name] = tb.DefaultValue
+	}
+	return &src
+}
+
+// lookupFormSource returns where a page form came from. formSources
+// is replaced by processPageForms while form goroutines still look
+// forms up so it's only read under formSourcesMu.
+func (b *ugglyBrowser) lookupFormSource(name string) (*formSource, bool) {
+	b.formSourcesMu.Lock()
+	defer b.formSourcesMu.Unlock()
+	src, ok := b.formSources[name]
+	return src, ok
+}
+
+// currentFormSources returns a copy of formSources to range over
+func (b *ugglyBrowser) currentFormSources() map[string]*formSource {
+	b.formSourcesMu.Lock()
+	defer b.formSourcesMu.Unlock()
+	sources := make(map[string]*formSource, len(b.formSources))
+	for name, src := range b.formSources {
+		sources[name] = src
+	}
+	return sources
+}
+
+// saveFormValues stores the current contents of a page form that just
+// lost focus. Menu forms have no source and are ignored.
+func (b *ugglyBrowser) saveFormValues(name string, f pageForm) {
+	src, ok := b.lookupFormSource(name)
+	if !ok {
+		return
+	}
+	b.formState.save(src.pageKey, name, f.Collect(), src.defaults)
+}
+
+// clearFormValues forgets stored values for a submitted page form
+func (b *ugglyBrowser) clearFormValues(name string) {
+	src, ok := b.lookupFormSource(name)
+	if !ok {
+		return
+	}
+	b.formState.clear(src.pageKey, name)
+}
+
+// findForm returns the current form, and its name, whose name matches
+// once the localAuthUuid is stripped so local forms can be found
+// again after they were rebuilt
+func (b *ugglyBrowser) findForm(key string) (string, pageForm) {
+	for _, f := range b.forms {
+		if formKey(f.Name) == key {
+			return f.Name, f
+		}
+	}
+	for _, wf := range b.widgetForms {
+		if formKey(wf.Name) == key {
+			return wf.Name, wf
+		}
+	}
+	return "", nil
+}
+
+// sizeChanged reports whether the screen no longer matches the
+// dimensions the current content was laid out for
+func (b *ugglyBrowser) sizeChanged() bool {
+	w, h := b.view.Size()
+	return w != b.vW || h-b.menuHeight != b.vH
+}
+
+// watchFormResize runs while a form is polling. Since the form owns
+// the event loop during that time the browser never sees the resize
+// event so we watch the screen size instead and cancel the form
+// so it can be laid out again.
+func (b *ugglyBrowser) watchFormResize(ctx context.Context, cancel context.CancelFunc, resized chan struct{}) {
+	ticker := time.NewTicker(b.resizeDelay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if b.sizeChanged() {
+				loggo.Info("detected resize during form poll")
+				resized <- struct{}{}
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// confirmSubmission asks the user before a form containing password
+// boxes is sent over an insecure connection or to a server other than
+// the one that served the form. Returns false if the user declined.
+func (b *ugglyBrowser) confirmSubmission(name string) bool {
+	src, ok := b.lookupFormSource(name)
+	if !ok || src.form.SubmitLink == nil {
+		return true
+	}
+	hasPassword := false
+	for _, tb := range src.form.TextBoxes {
+		if tb.Password {
+			hasPassword = true
+		}
+	}
+	if !hasPassword {
+		return true
+	}
+	dest, _ := b.linkFiller(src.form.SubmitLink)
+	reasons := []string{}
+	if !dest.Secure {
+		reasons = append(reasons, "the connection is not encrypted")
+	}
+	server := fmt.Sprintf("%s:%s", dest.Server, dest.Port)
+	if dest.Server != src.origin || dest.Port != src.originPort {
+		// another port can be another service, so it counts too
+		reasons = append(reasons, fmt.Sprintf(
+			"it will be sent to '%s' instead of '%s:%s'", server, src.origin, src.originPort))
+	}
+	if len(reasons) == 0 {
+		return true
+	}
+	if b.settings.passwordFormsAllowed(server) {
+		loggo.Info("password form allowed by settings", "server", server)
+		return true
+	}
+	msg := fmt.Sprintf("This form contains a password but %s.\n\n"+
+		"(y) send anyway\n(a) always allow for %s\n(n) cancel",
+		strings.Join(reasons, " and "), server)
+	switch b.promptKey("Insecure form submission", msg, []rune{'y', 'a', 'n'}) {
+	case 'y':
+		return true
+	case 'a':
+		b.settingsMu.Lock()
+		b.settings.allowPasswordForms(server)
+		b.settingsMu.Unlock()
+		err := b.settingsSave()
+		if err != nil {
+			go b.sendMessage("error saving settings to disk", "form-confirm")
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/inconshreveable/log15"
+	pb "github.com/rendicott/uggly"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	loggo = log15.New()
+	loggo.SetHandler(log15.DiscardHandler())
+	os.Exit(m.Run())
+}
+
+func loginForm() *pb.Form {
+	return &pb.Form{
+		Name: "login",
+		TextBoxes: []*pb.TextBox{
+			{Name: "user", DefaultValue: "guest"},
+			{Name: "note"},
+		},
+	}
+}
+
+// formDefaults is what newFormSource reads from a page form
+func formDefaults(form *pb.Form) map[string]string {
+	defaults := make(map[string]string)
+	for _, tb := range form.TextBoxes {
+		defaults[tb.Name] = tb.DefaultValue
+	}
+	return defaults
+}
+
+func formValues(form *pb.Form) map[string]string {
+	return formDefaults(form)
+}
+
+func TestFormStateSave(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   map[string]map[string]string
+	}{
+		{"nothing changed", map[string]string{"user": "guest", "note": ""},
+			map[string]map[string]string{}},
+		{"changed field", map[string]string{"user": "bob", "note": ""},
+			map[string]map[string]string{"login": {"user": "bob"}}},
+		{"cleared default", map[string]string{"user": "", "note": ""},
+			map[string]map[string]string{"login": {"user": ""}}},
+		{"field the server didn't send", map[string]string{"user": "guest", "extra": "x"},
+			map[string]map[string]string{"login": {"extra": "x"}}},
+	}
+	for _, tt := range tests {
+		fs := newFormState()
+		fs.save("page", "login", tt.values, formDefaults(loginForm()))
+		if got := fs.snapshot("page"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: stored %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormStateSaveBackToDefaults(t *testing.T) {
+	fs := newFormState()
+	defaults := formDefaults(loginForm())
+	fs.save("page", "login", map[string]string{"user": "bob"}, defaults)
+	fs.save("page", "login", map[string]string{"user": "guest"}, defaults)
+	if fs.has("page", "login") {
+		t.Errorf("form typed back to its defaults should be dropped")
+	}
+}
+
+func TestFormStateApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		saved map[string]string
+		want  map[string]string
+	}{
+		{"nothing saved", nil, map[string]string{"user": "guest", "note": ""}},
+		{"saved field", map[string]string{"user": "bob"},
+			map[string]string{"user": "bob", "note": ""}},
+		{"saved field the form doesn't have", map[string]string{"gone": "x"},
+			map[string]string{"user": "guest", "note": ""}},
+	}
+	for _, tt := range tests {
+		fs := newFormState()
+		if tt.saved != nil {
+			fs.restore("page", map[string]map[string]string{"login": tt.saved})
+		}
+		form := loginForm()
+		shown := fs.apply("page", form)
+		if got := formValues(shown); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: shows %v, want %v", tt.name, got, tt.want)
+		}
+		if got := formValues(form); !reflect.DeepEqual(got, formValues(loginForm())) {
+			t.Errorf("%s: apply changed the page's form to %v", tt.name, got)
+		}
+	}
+}
+
+// TestFormStateRebuild follows a form through the rebuilds the browser
+// does on its own, e.g., for every status message, before a refresh
+func TestFormStateRebuild(t *testing.T) {
+	fs := newFormState()
+	page := loginForm()
+	// the user types and tabs out
+	fs.save("page", "login", map[string]string{"user": "bob", "note": ""}, formDefaults(page))
+	// a status message rebuilds the forms from the same page
+	shown := fs.apply("page", page)
+	// the user tabs through again without changing anything
+	fs.save("page", "login", formValues(shown), formDefaults(page))
+	// and then refreshes
+	shown = fs.apply("page", page)
+	if got := formValues(shown)["user"]; got != "bob" {
+		t.Errorf("after rebuild, save and refresh user = %q, want bob", got)
+	}
+}
@@ -4,8 +4,12 @@ go 1.15
 
 replace github.com/rendicott/uggly-client/boxes => ./boxes
 
+replace github.com/rendicott/uggly-client/cookiejar => ./cookiejar
+
 replace github.com/rendicott/uggly-client/ugcon => ./ugcon
 
+replace github.com/rendicott/uggly-client/widgets => ./widgets
+
 replace github.com/rendicott/uggly => ../uggly
 
 replace github.com/rendicott/uggo => ../uggo
@@ -23,9 +27,13 @@ require (
 	github.com/rendicott/ugform v0.0.2
 	github.com/rendicott/uggly v0.1.2
 	github.com/rendicott/uggly-client/boxes v0.0.0
+	github.com/rendicott/uggly-client/cookiejar v0.0.0
 	github.com/rendicott/uggly-client/ugcon v0.0.0
+	github.com/rendicott/uggly-client/widgets v0.0.0
 	github.com/rendicott/uggo v0.0.2
 	github.com/rendicott/uggsec v0.0.0-20220417162920-8d8282e3a927
+	github.com/zalando/go-keyring v0.2.1
 	google.golang.org/grpc v1.45.0
+	google.golang.org/protobuf v1.28.0
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	pb "github.com/rendicott/uggly"
+	"strings"
+)
+
+// keyHelp is one line of the help overlay
+type keyHelp struct {
+	keys   string
+	action string
+	note   string // why the keystroke won't fire as expected, if it won't
+}
+
+// keyStrokeAction describes what a keystroke does for the help overlay
+func keyStrokeAction(ks *pb.KeyStroke) string {
+	switch x := ks.Action.(type) {
+	case *pb.KeyStroke_Link:
+		if x.Link == nil {
+			return "link: none"
+		}
+		if x.Link.Server == "MENU" {
+			return "menu: " + strings.ToLower(x.Link.PageName)
+		}
+		if len(localAuthUuid) > 1 && strings.Contains(x.Link.PageName, localAuthUuid) {
+			return "local: " + strings.TrimSuffix(
+				strings.Replace(x.Link.PageName, localAuthUuid, "", 1), "_")
+		}
+		proto := "ugtp://"
+		if x.Link.Secure {
+			proto = "ugtps://"
+		}
+		return fmt.Sprintf("link: %s%s:%s/%s", proto, x.Link.Server, x.Link.Port, x.Link.PageName)
+	case *pb.KeyStroke_FormActivation:
+		return "form: " + formKey(x.FormActivation.FormName)
+	case *pb.KeyStroke_DivScroll:
+		return "scroll: " + x.DivScroll.String()
+	}
+	return "unknown action"
+}
+
+// keyStrokeHelp lists the menu keys followed by the page's keystrokes
+// and flags the ones that won't fire. The precedence is: menu keys
+// always win, then the first page keystroke for a key in the order the
+// server sent them. A key that starts a longer chord waits chordDelay
+// for the rest of it before firing.
+func (b *ugglyBrowser) keyStrokeHelp() (menu, page []keyHelp) {
+	bindings := b.settings.keyBindings()
+	menuOwner := make(map[keySpec]string)
+	for _, kb := range bindings {
+		note := ""
+		if first, ok := menuOwner[kb.key]; ok {
+			note = fmt.Sprintf("shadowed by menu %s", first)
+		} else {
+			menuOwner[kb.key] = kb.name
+		}
+		menu = append(menu, keyHelp{kb.key.String(), "menu: " + kb.name, note})
+	}
+	isMenu := make(map[*pb.KeyStroke]bool)
+	for _, ks := range b.menuKeyStrokes {
+		isMenu[ks] = true
+	}
+	chords := [][]keySpec{}
+	for _, ks := range b.activeKeyStrokes {
+		if isMenu[ks] {
+			continue
+		}
+		chord, err := parseChord(ks.KeyStroke)
+		chords = append(chords, chord)
+		entry := keyHelp{ks.KeyStroke, keyStrokeAction(ks), ""}
+		if ks.KeyStroke == "" {
+			entry.note = "no key, reachable from hint mode"
+			page = append(page, entry)
+			continue
+		}
+		if err != nil {
+			entry.note = fmt.Sprintf("never fires, %s", err.Error())
+			page = append(page, entry)
+			continue
+		}
+		entry.keys = chordString(chord)
+		if owner, ok := menuOwner[chord[0]]; ok {
+			entry.note = fmt.Sprintf("shadowed by menu %s", owner)
+		}
+		for i, other := range chords[:len(chords)-1] {
+			if entry.note != "" {
+				break
+			}
+			if other != nil && chordString(other) == entry.keys {
+				entry.note = fmt.Sprintf("duplicate of line %d, ignored", i+1)
+			}
+		}
+		page = append(page, entry)
+	}
+	for i, entry := range page {
+		if entry.note != "" || chords[i] == nil {
+			continue
+		}
+		for _, other := range chords {
+			if len(other) > len(chords[i]) &&
+				strings.HasPrefix(chordString(other), entry.keys+" ") {
+				page[i].note = fmt.Sprintf("waits %s for chord '%s'", b.chordDelay, chordString(other))
+				break
+			}
+		}
+	}
+	return menu, page
+}
+
+// keyStrokeConflicts counts the page keystrokes that won't fire
+func (b *ugglyBrowser) keyStrokeConflicts() int {
+	_, page := b.keyStrokeHelp()
+	count := 0
+	for _, entry := range page {
+		if entry.note != "" && !strings.HasPrefix(entry.note, "waits") &&
+			!strings.HasPrefix(entry.note, "no key") {
+			count++
+		}
+	}
+	return count
+}
+
+// helpOverlay shows every active keystroke a screenful at a time until
+// the user closes it. Stream frames wait until it's closed.
+func (b *ugglyBrowser) helpOverlay() {
+	b.overlayMu.Lock()
+	defer b.overlayMu.Unlock()
+	menu, page := b.keyStrokeHelp()
+	format := func(entry keyHelp) string {
+		line := fmt.Sprintf("%-16s %s", entry.keys, entry.action)
+		if entry.note != "" {
+			line += "  !! " + entry.note
+		}
+		return line
+	}
+	lines := []string{"Menu keys (always win):"}
+	for _, entry := range menu {
+		lines = append(lines, "  "+format(entry))
+	}
+	lines = append(lines, "", "Page keys (first one for a key wins):")
+	if len(page) == 0 {
+		lines = append(lines, "  none")
+	}
+	for i, entry := range page {
+		lines = append(lines, fmt.Sprintf("%2d %s", i+1, format(entry)))
+	}
+	perPage := b.vH + b.menuHeight - 10
+	if perPage < 3 {
+		perPage = 3
+	}
+	for offset := 0; ; {
+		end := offset + perPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		msg := strings.Join(lines[offset:end], "\n")
+		msg += fmt.Sprintf("\n\n(%d-%d of %d lines) (n) next (p) previous (q) close",
+			offset+1, end, len(lines))
+		switch b.promptKey("Keystrokes", msg, []rune{'n', 'p', 'q', '?'}) {
+		case 'n':
+			if end < len(lines) {
+				offset = end
+			}
+		case 'p':
+			offset -= perPage
+			if offset < 0 {
+				offset = 0
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"github.com/gdamore/tcell/v2"
+	pb "github.com/rendicott/uggly"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// hintAlphabet is what hint labels are made of, home row first
+const hintAlphabet = "asdfghjklqwertyuiopzxcvbnm"
+
+// hintTarget is a link or form on the page that hint mode can activate
+// whether or not the server gave it a usable keystroke
+type hintTarget struct {
+	ks     *pb.KeyStroke
+	label  string
+	x      int
+	y      int
+	placed bool // found on screen, otherwise listed at the bottom
+}
+
+// hintLabels returns count labels of the same length so no label is
+// the start of another one and each is typed without waiting
+func hintLabels(count int) []string {
+	alphabet := []rune(hintAlphabet)
+	size := 1
+	for n := len(alphabet); n < count; n *= len(alphabet) {
+		size++
+	}
+	labels := []string{}
+	for i := 0; i < count; i++ {
+		label := make([]rune, size)
+		for j, rest := size-1, i; j >= 0; j-- {
+			label[j] = alphabet[rest%len(alphabet)]
+			rest /= len(alphabet)
+		}
+		labels = append(labels, string(label))
+	}
+	return labels
+}
+
+// screenRows reads back the page content below the menu so targets can
+// be found by the text that mentions them
+func (b *ugglyBrowser) screenRows() []string {
+	w, h := b.view.Size()
+	rows := []string{}
+	for y := b.menuHeight; y < h; y++ {
+		row := make([]rune, w)
+		for x := 0; x < w; x++ {
+			row[x], _, _, _ = b.view.GetContent(x, y)
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+// locate finds the first unclaimed spot on screen showing text
+func (b *ugglyBrowser) locate(rows []string, text string, claimed map[[2]int]bool) (int, int, bool) {
+	if strings.TrimSpace(text) == "" {
+		return 0, 0, false
+	}
+	for y, row := range rows {
+		from := 0
+		for {
+			i := strings.Index(row[from:], text)
+			if i < 0 {
+				break
+			}
+			x := utf8.RuneCountInString(row[:from+i])
+			if !claimed[[2]int{x, y}] {
+				claimed[[2]int{x, y}] = true
+				return x, y + b.menuHeight, true
+			}
+			from += i + len(text)
+		}
+	}
+	return 0, 0, false
+}
+
+// hintTargets collects every link and form on the page. Keystroke links
+// are placed over the "(key)" text pages conventionally show next to
+// them or failing that over their page name, forms over their first
+// textbox. Targets read top to bottom, then the ones that weren't found.
+func (b *ugglyBrowser) hintTargets() []*hintTarget {
+	isMenu := make(map[*pb.KeyStroke]bool)
+	for _, ks := range b.menuKeyStrokes {
+		isMenu[ks] = true
+	}
+	rows := b.screenRows()
+	claimed := make(map[[2]int]bool)
+	targets := []*hintTarget{}
+	activated := make(map[string]bool)
+	for _, ks := range b.activeKeyStrokes {
+		if isMenu[ks] {
+			continue
+		}
+		t := &hintTarget{ks: ks}
+		switch x := ks.Action.(type) {
+		case *pb.KeyStroke_Link:
+			if x.Link == nil {
+				continue
+			}
+			if ks.KeyStroke != "" {
+				t.x, t.y, t.placed = b.locate(rows, "("+ks.KeyStroke+")", claimed)
+			}
+			if !t.placed {
+				t.x, t.y, t.placed = b.locate(rows, x.Link.PageName, claimed)
+			}
+		case *pb.KeyStroke_FormActivation:
+			activated[formKey(x.FormActivation.FormName)] = true
+			if src, ok := b.lookupFormSource(x.FormActivation.FormName); ok {
+				t.x, t.y, t.placed = formHintSpot(src)
+			}
+		default:
+			continue
+		}
+		targets = append(targets, t)
+	}
+	sources := b.currentFormSources()
+	names := []string{}
+	for name := range sources {
+		if !activated[formKey(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := &hintTarget{ks: &pb.KeyStroke{
+			Action: &pb.KeyStroke_FormActivation{
+				FormActivation: &pb.FormActivation{FormName: name},
+			}}}
+		t.x, t.y, t.placed = formHintSpot(sources[name])
+		targets = append(targets, t)
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		if targets[i].placed != targets[j].placed {
+			return targets[i].placed
+		}
+		if targets[i].y != targets[j].y {
+			return targets[i].y < targets[j].y
+		}
+		return targets[i].x < targets[j].x
+	})
+	for i, label := range hintLabels(len(targets)) {
+		targets[i].label = label
+	}
+	return targets
+}
+
+// formHintSpot is the screen position of a form's first textbox
+func formHintSpot(src *formSource) (int, int, bool) {
+	if src == nil || src.form == nil || len(src.form.TextBoxes) == 0 {
+		return 0, 0, false
+	}
+	tb := src.form.TextBoxes[0]
+	return src.shiftX + int(tb.PositionX), src.shiftY + int(tb.PositionY), true
+}
+
+// drawHints puts the labels still matching typed over the content,
+// targets that weren't found on screen are listed from the bottom up
+func (b *ugglyBrowser) drawHints(targets []*hintTarget, typed string) {
+	style := tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow)
+	w, h := b.view.Size()
+	put := func(x, y int, s string) {
+		for _, r := range s {
+			if x >= w || y >= h {
+				return
+			}
+			b.view.SetContent(x, y, r, nil, style)
+			x++
+		}
+	}
+	listY := h - 1
+	for _, t := range targets {
+		if !strings.HasPrefix(t.label, typed) {
+			continue
+		}
+		if t.placed {
+			put(t.x, t.y, t.label)
+			continue
+		}
+		if listY < b.menuHeight {
+			continue
+		}
+		put(0, listY, " "+t.label+" "+keyStrokeAction(t.ks)+" ")
+		listY--
+	}
+	b.view.Show()
+}
+
+// hintMode labels every link and form on the page and activates the
+// one whose label is typed. Escape or a key that matches no label
+// leaves hint mode. Like promptKey it polls the screen itself so it
+// must only run on the event loop.
+func (b *ugglyBrowser) hintMode(ctx context.Context) {
+	// stream frames would draw over the labels and swap the keystrokes
+	// they point to, so they wait until a hint is picked
+	b.overlayMu.Lock()
+	ks := b.pickHint()
+	b.overlayMu.Unlock()
+	if ks == nil {
+		return
+	}
+	loggo.Info("activating hint", "action", keyStrokeAction(ks))
+	b.cexCancel <- "user-cancel" // like a page key, stops the page's stream
+	b.keyStrokeRouter(ctx, ks)
+}
+
+// pickHint shows the labels and returns the keystroke of the one typed
+// or nil if the user left hint mode
+func (b *ugglyBrowser) pickHint() *pb.KeyStroke {
+	targets := b.hintTargets()
+	if len(targets) == 0 {
+		go b.sendMessage("no links or forms on this page", "hints")
+		return nil
+	}
+	loggo.Debug("entering hint mode", "targets", len(targets))
+	typed := ""
+	b.drawHints(targets, typed)
+	for {
+		ev := b.view.PollEvent()
+		kev, ok := ev.(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		switch kev.Key() {
+		case tcell.KeyEscape:
+			b.drawContent("hints-close")
+			return nil
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if typed != "" {
+				typed = typed[:len(typed)-1]
+			}
+			b.drawContent("hints-redraw")
+			b.drawHints(targets, typed)
+			continue
+		case tcell.KeyRune:
+		default:
+			continue
+		}
+		typed += string(unicode.ToLower(kev.Rune()))
+		matching := 0
+		for _, t := range targets {
+			if t.label == typed {
+				b.drawContent("hints-close")
+				loggo.Debug("picked hint", "label", typed)
+				return t.ks
+			}
+			if strings.HasPrefix(t.label, typed) {
+				matching++
+			}
+		}
+		b.drawContent("hints-redraw")
+		if matching == 0 {
+			return nil
+		}
+		b.drawHints(targets, typed)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHintLabels(t *testing.T) {
+	tests := []struct {
+		count    int
+		wantSize int
+	}{
+		{0, 0},
+		{1, 1},
+		{len(hintAlphabet), 1},
+		{len(hintAlphabet) + 1, 2},
+		{len(hintAlphabet) * len(hintAlphabet), 2},
+		{len(hintAlphabet)*len(hintAlphabet) + 1, 3},
+	}
+	for _, tt := range tests {
+		labels := hintLabels(tt.count)
+		if len(labels) != tt.count {
+			t.Errorf("%d: got %d labels", tt.count, len(labels))
+			continue
+		}
+		seen := make(map[string]bool)
+		for i, label := range labels {
+			if len(label) != tt.wantSize {
+				t.Errorf("%d: label %q is %d long, want %d", tt.count, label, len(label), tt.wantSize)
+			}
+			if seen[label] {
+				t.Errorf("%d: label %q used twice", tt.count, label)
+			}
+			seen[label] = true
+			for _, other := range labels[i+1:] {
+				if strings.HasPrefix(other, label) || strings.HasPrefix(label, other) {
+					t.Errorf("%d: %q and %q, one starts the other", tt.count, label, other)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// historyFileName is stored next to the cookie vault so every profile
+// keeps its own history
+const historyFileName = "history.json"
+
+// historyMax is how many pages the history remembers
+const historyMax = 50
+
+// historyEntry is a page the browser loaded, newest first in b.history
+type historyEntry struct {
+	Ugri    string
+	Name    string // PageResponse name the server gave the page
+	Visited string // RFC1123 time of the last visit
+}
+
+func (b *ugglyBrowser) historyFile() string {
+	return filepath.Join(filepath.Dir(*b.settings.VaultFile), historyFileName)
+}
+
+func (b *ugglyBrowser) loadHistory() (err error) {
+	dat, err := ioutil.ReadFile(b.historyFile())
+	if err != nil {
+		return err
+	}
+	history := []*historyEntry{}
+	err = json.Unmarshal(dat, &history)
+	if err != nil {
+		return err
+	}
+	b.history = history
+	loggo.Info("loaded history", "num_pages", len(history))
+	return err
+}
+
+func (b *ugglyBrowser) storeHistory() (err error) {
+	dat, err := json.MarshalIndent(b.history, "", "  ")
+	if err != nil {
+		return err
+	}
+	ensureDir(b.historyFile())
+	return ioutil.WriteFile(b.historyFile(), dat, 0600)
+}
+
+// recordHistory moves the page that was just loaded to the top of the
+// history, dropping the oldest pages past historyMax
+func (b *ugglyBrowser) recordHistory() {
+	ugri := *b.sess.genUgri()
+	entry := &historyEntry{
+		Ugri:    ugri,
+		Name:    b.currentPage.Name,
+		Visited: time.Now().Format(time.RFC1123),
+	}
+	history := []*historyEntry{entry}
+	for _, h := range b.history {
+		if normalizeUgri(h.Ugri) != normalizeUgri(ugri) && len(history) < historyMax {
+			history = append(history, h)
+		}
+	}
+	b.history = history
+	err := b.storeHistory()
+	if err != nil {
+		loggo.Error("error storing history", "err", err.Error())
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	pb "github.com/rendicott/uggly"
+	"sort"
+	"strings"
+)
+
+// the client actions a key can be bound to in the keymap setting
+const (
+	actionAddress     = "address"
+	actionColorDemo   = "colordemo"
+	actionSettings    = "settings"
+	actionFeed        = "feed"
+	actionRefresh     = "refresh"
+	actionBookmarks   = "bookmarks"
+	actionAddBookmark = "addbookmark"
+	actionLogins      = "logins"
+	actionCookies     = "cookies"
+	actionExit        = "exit"
+	actionProfiles    = "profiles"
+	actionHome        = "home"
+	actionCancel      = "cancel"
+	actionHelp        = "help"
+	actionHints       = "hints"
+)
+
+// menuAction describes an action for the keymap and the menu bar,
+// actions without a label aren't shown in the menu
+type menuAction struct {
+	name       string
+	label      string
+	defaultKey string
+}
+
+// menuActions is in the order the menu shows them which is also the
+// precedence if two actions end up on the same key
+var menuActions = []menuAction{
+	{actionAddress, "", "F1"},
+	{actionColorDemo, "ColorDemo", "F2"},
+	{actionSettings, "Settings", "F3"},
+	{actionFeed, "Browse Feed", "F4"},
+	{actionRefresh, "Refresh", "F5"},
+	{actionBookmarks, "Bookmarks", "F6"},
+	{actionAddBookmark, "AddBookmark", "F7"},
+	{actionLogins, "Logins", "F8"},
+	{actionCookies, "Cookies", "F9"},
+	{actionExit, "Exit", "F10"},
+	{actionProfiles, "Profiles", "F11"},
+	{actionHome, "Home", "F12"},
+	{actionHelp, "Help", "?"},
+	{actionHints, "Hints", "Ctrl-F"},
+	{actionCancel, "", "Ctrl-L"},
+}
+
+// keyBinding is an action with the key it is bound to
+type keyBinding struct {
+	menuAction
+	key keySpec
+}
+
+func validAction(name string) bool {
+	for _, a := range menuActions {
+		if a.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// keyBindings returns every action bound to the key from the keymap
+// setting or its default key
+func (s *ugglyBrowserSettings) keyBindings() []keyBinding {
+	bindings := []keyBinding{}
+	for _, a := range menuActions {
+		spec, err := parseKey(a.defaultKey)
+		if k, ok := s.Keymap[a.name]; ok {
+			if custom, cerr := parseKey(k); cerr == nil {
+				spec, err = custom, nil
+			}
+		}
+		if err != nil {
+			continue
+		}
+		bindings = append(bindings, keyBinding{menuAction: a, key: spec})
+	}
+	return bindings
+}
+
+// actionKey returns the name of the key bound to the action for hints
+// shown to the user
+func (s *ugglyBrowserSettings) actionKey(action string) string {
+	for _, kb := range s.keyBindings() {
+		if kb.name == action {
+			return kb.key.String()
+		}
+	}
+	return "none"
+}
+
+// keyAction returns the action bound to the key event, if any
+func (s *ugglyBrowserSettings) keyAction(ev *tcell.EventKey) string {
+	for _, kb := range s.keyBindings() {
+		if kb.key.matches(ev) {
+			return kb.name
+		}
+	}
+	return ""
+}
+
+// keymapConflicts describes actions sharing a key, the first action
+// in menuActions order wins
+func (s *ugglyBrowserSettings) keymapConflicts() []string {
+	conflicts := []string{}
+	owner := make(map[string]string)
+	for _, kb := range s.keyBindings() {
+		key := kb.key.String()
+		if first, ok := owner[key]; ok {
+			conflicts = append(conflicts, fmt.Sprintf(
+				"%s and %s are both on %s, %s wins", first, kb.name, key, first))
+			continue
+		}
+		owner[key] = kb.name
+	}
+	return conflicts
+}
+
+// parseKeymap reads "action=key" pairs separated by commas and returns
+// the valid ones along with any that weren't understood
+func parseKeymap(value string) (map[string]string, []string) {
+	keymap := make(map[string]string)
+	bad := []string{}
+	for _, entry := range splitList(value) {
+		chunks := strings.SplitN(entry, "=", 2)
+		if len(chunks) != 2 {
+			bad = append(bad, entry)
+			continue
+		}
+		action := strings.ToLower(strings.TrimSpace(chunks[0]))
+		spec, err := parseKey(chunks[1])
+		if !validAction(action) || err != nil {
+			bad = append(bad, entry)
+			continue
+		}
+		keymap[action] = spec.String()
+	}
+	return keymap, bad
+}
+
+func formatKeymap(keymap map[string]string) string {
+	entries := []string{}
+	for action, key := range keymap {
+		entries = append(entries, fmt.Sprintf("%s=%s", action, key))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// menuKeyStrokes builds the menu's keystrokes from the keymap. Only the
+// address bar does anything through them, pollEvents catches the keys
+// of every other action first, but they let the rest of the browser
+// know which keys the menu owns.
+func menuKeyStrokes(bindings []keyBinding) []*pb.KeyStroke {
+	keyStrokes := []*pb.KeyStroke{}
+	for _, kb := range bindings {
+		ks := &pb.KeyStroke{KeyStroke: kb.key.String()}
+		if kb.name == actionAddress {
+			ks.Action = &pb.KeyStroke_FormActivation{
+				FormActivation: &pb.FormActivation{
+					FormName: "address-bar",
+				},
+			}
+		} else {
+			ks.Action = &pb.KeyStroke_Link{
+				Link: &pb.Link{
+					PageName: strings.ToUpper(kb.name),
+					Server:   "MENU",
+					Port:     "0",
+				},
+			}
+		}
+		keyStrokes = append(keyStrokes, ks)
+	}
+	return keyStrokes
+}
+
+// runAction performs a client action from the keymap. Returns false
+// once the browser is exiting.
+func (b *ugglyBrowser) runAction(ctx context.Context, action string) bool {
+	loggo.Debug("running keymap action", "action", action)
+	// help and hints are read over the page, streams keep going
+	if action != actionHelp && action != actionHints {
+		b.cexCancel <- "user-cancel"
+	}
+	switch action {
+	case actionExit:
+		b.exit(0)
+		return false
+	case actionCancel:
+		loggo.Info("kill context")
+	case actionAddress:
+		b.keyStrokeRouter(ctx, &pb.KeyStroke{
+			Action: &pb.KeyStroke_FormActivation{
+				FormActivation: &pb.FormActivation{
+					FormName: "address-bar",
+				},
+			}})
+	case actionFeed:
+		b.getFeed(ctx)
+	case actionColorDemo:
+		b.colorDemo()
+	case actionSettings:
+		b.settingsPage("")
+	case actionRefresh:
+		b.refresh(ctx)
+	case actionBookmarks:
+		b.bookmarksPage()
+	case actionAddBookmark:
+		b.bookmarkAdd()
+	case actionLogins:
+		b.credentialsPage("")
+	case actionCookies:
+		b.cookiesPage("")
+	case actionProfiles:
+		b.profilesPage("")
+	case actionHome:
+		b.goHome(ctx)
+	case actionHelp:
+		b.helpOverlay()
+	case actionHints:
+		b.hintMode(ctx)
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"strings"
+	"unicode"
+)
+
+// keySpec is a single key press with modifiers parsed from strings
+// like "F2", "Ctrl-L", "Alt-x" or "Shift-F5"
+type keySpec struct {
+	key tcell.Key // tcell.KeyRune when r is set
+	r   rune
+	mod tcell.ModMask
+}
+
+var keyModNames = []struct {
+	name string
+	mod  tcell.ModMask
+}{
+	{"Ctrl", tcell.ModCtrl},
+	{"Alt", tcell.ModAlt},
+	{"Meta", tcell.ModMeta},
+	{"Shift", tcell.ModShift},
+}
+
+// isCtrlKey is true for the tcell keys that already mean Ctrl-<letter>,
+// tcell reports those with ModCtrl set as well
+func isCtrlKey(k tcell.Key) bool {
+	return k >= tcell.KeyCtrlA && k <= tcell.KeyCtrlZ
+}
+
+// parseKey turns a key name into a keySpec. Modifiers are separated by
+// '-' or '+' and names are matched without regard to case except for
+// single characters, e.g., "ctrl+l", "Alt-X", "F10", "Esc".
+func parseKey(s string) (keySpec, error) {
+	spec := keySpec{}
+	rest := strings.TrimSpace(s)
+	for found := true; found; {
+		found = false
+		for _, m := range keyModNames {
+			if len(rest) > len(m.name)+1 && strings.EqualFold(rest[:len(m.name)], m.name) &&
+				(rest[len(m.name)] == '-' || rest[len(m.name)] == '+') {
+				spec.mod |= m.mod
+				rest = rest[len(m.name)+1:]
+				found = true
+			}
+		}
+	}
+	if rest == "" {
+		return spec, fmt.Errorf("no key in '%s'", s)
+	}
+	runes := []rune(rest)
+	if strings.EqualFold(rest, "space") {
+		runes = []rune{' '}
+	}
+	if len(runes) == 1 {
+		r := runes[0]
+		if spec.mod&tcell.ModCtrl != 0 && unicode.IsLetter(r) && r < unicode.MaxASCII {
+			// terminals send Ctrl-<letter> as its own key
+			spec.key = tcell.KeyCtrlA + tcell.Key(unicode.ToLower(r)-'a')
+			spec.mod &^= tcell.ModCtrl
+			return spec, nil
+		}
+		spec.key = tcell.KeyRune
+		spec.r = r
+		// shift is part of the rune itself, e.g., 'X'
+		spec.mod &^= tcell.ModShift
+		return spec, nil
+	}
+	for k, name := range tcell.KeyNames {
+		if strings.EqualFold(name, rest) {
+			spec.key = k
+			if isCtrlKey(k) {
+				spec.mod &^= tcell.ModCtrl
+			}
+			return spec, nil
+		}
+	}
+	return spec, fmt.Errorf("unknown key '%s'", rest)
+}
+
+// matches reports whether the key event is this key
+func (k keySpec) matches(ev *tcell.EventKey) bool {
+	mods := ev.Modifiers()
+	if ev.Key() == tcell.KeyRune {
+		mods &^= tcell.ModShift
+	}
+	if isCtrlKey(ev.Key()) {
+		mods &^= tcell.ModCtrl
+	}
+	if ev.Key() != k.key || mods != k.mod {
+		return false
+	}
+	return k.key != tcell.KeyRune || ev.Rune() == k.r
+}
+
+// String is the canonical name for the key, parseKey understands it
+func (k keySpec) String() string {
+	name := ""
+	for _, m := range keyModNames {
+		if k.mod&m.mod != 0 {
+			name += m.name + "-"
+		}
+	}
+	if k.key == tcell.KeyRune {
+		if k.r == ' ' {
+			return name + "Space"
+		}
+		return name + string(k.r)
+	}
+	if keyName, ok := tcell.KeyNames[k.key]; ok {
+		return name + keyName
+	}
+	return fmt.Sprintf("%sKey[%d]", name, int(k.key))
+}
+
+// parseChord parses a keystroke that may be a sequence of keys pressed
+// one after the other separated by spaces, e.g., "g g" or
+// "Ctrl-X Ctrl-S". A keystroke of just " " is the space key.
+func parseChord(s string) ([]keySpec, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s == "" {
+			return nil, fmt.Errorf("empty keystroke")
+		}
+		fields = []string{"Space"}
+	}
+	chord := []keySpec{}
+	for _, field := range fields {
+		spec, err := parseKey(field)
+		if err != nil {
+			return nil, err
+		}
+		chord = append(chord, spec)
+	}
+	return chord, nil
+}
+
+func chordString(chord []keySpec) string {
+	names := []string{}
+	for _, k := range chord {
+		names = append(names, k.String())
+	}
+	return strings.Join(names, " ")
+}
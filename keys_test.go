@@ -0,0 +1,115 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"reflect"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    keySpec
+		wantStr string
+		wantErr bool
+	}{
+		{"F2", keySpec{key: tcell.KeyF2}, "F2", false},
+		{"f10", keySpec{key: tcell.KeyF10}, "F10", false},
+		{"Esc", keySpec{key: tcell.KeyEscape}, "Esc", false},
+		{"Ctrl-L", keySpec{key: tcell.KeyCtrlL}, "Ctrl-L", false},
+		{"ctrl+l", keySpec{key: tcell.KeyCtrlL}, "Ctrl-L", false},
+		{"Alt-x", keySpec{key: tcell.KeyRune, r: 'x', mod: tcell.ModAlt}, "Alt-x", false},
+		{"Alt-X", keySpec{key: tcell.KeyRune, r: 'X', mod: tcell.ModAlt}, "Alt-X", false},
+		{"Shift-F5", keySpec{key: tcell.KeyF5, mod: tcell.ModShift}, "Shift-F5", false},
+		{"Shift-x", keySpec{key: tcell.KeyRune, r: 'x'}, "x", false},
+		{"Ctrl-Alt-F1", keySpec{key: tcell.KeyF1, mod: tcell.ModCtrl | tcell.ModAlt}, "Ctrl-Alt-F1", false},
+		{"?", keySpec{key: tcell.KeyRune, r: '?'}, "?", false},
+		{"-", keySpec{key: tcell.KeyRune, r: '-'}, "-", false},
+		{"Ctrl--", keySpec{key: tcell.KeyRune, r: '-', mod: tcell.ModCtrl}, "Ctrl--", false},
+		{"space", keySpec{key: tcell.KeyRune, r: ' '}, "Space", false},
+		{" g ", keySpec{key: tcell.KeyRune, r: 'g'}, "g", false},
+		{"", keySpec{}, "", true},
+		{"Ctrl-", keySpec{}, "", true},
+		{"Hyper-x", keySpec{}, "", true},
+		{"F99", keySpec{}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseKey(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseKey(%q) err %v, want error %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseKey(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.wantStr {
+			t.Errorf("parseKey(%q) is called %q, want %q", tt.in, got.String(), tt.wantStr)
+		}
+		if again, err := parseKey(got.String()); err != nil || again != got {
+			t.Errorf("parseKey(%q) doesn't read back its own name %q", tt.in, got.String())
+		}
+	}
+}
+
+func TestParseChord(t *testing.T) {
+	g := keySpec{key: tcell.KeyRune, r: 'g'}
+	tests := []struct {
+		in      string
+		want    []keySpec
+		wantErr bool
+	}{
+		{"g", []keySpec{g}, false},
+		{"g g", []keySpec{g, g}, false},
+		{"  g   g ", []keySpec{g, g}, false},
+		{"Ctrl-X Ctrl-S", []keySpec{{key: tcell.KeyCtrlX}, {key: tcell.KeyCtrlS}}, false},
+		{" ", []keySpec{{key: tcell.KeyRune, r: ' '}}, false},
+		{"", nil, true},
+		{"g Hyper-x", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseChord(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseChord(%q) err %v, want error %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseChord(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKeySpecMatches(t *testing.T) {
+	tests := []struct {
+		spec string
+		ev   *tcell.EventKey
+		want bool
+	}{
+		{"F2", tcell.NewEventKey(tcell.KeyF2, 0, tcell.ModNone), true},
+		{"F2", tcell.NewEventKey(tcell.KeyF3, 0, tcell.ModNone), false},
+		{"F2", tcell.NewEventKey(tcell.KeyF2, 0, tcell.ModShift), false},
+		{"Shift-F5", tcell.NewEventKey(tcell.KeyF5, 0, tcell.ModShift), true},
+		{"Ctrl-L", tcell.NewEventKey(tcell.KeyCtrlL, 0, tcell.ModCtrl), true},
+		{"Ctrl-L", tcell.NewEventKey(tcell.KeyCtrlL, 0, tcell.ModNone), true},
+		{"Ctrl-L", tcell.NewEventKey(tcell.KeyCtrlK, 0, tcell.ModCtrl), false},
+		{"x", tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone), true},
+		{"x", tcell.NewEventKey(tcell.KeyRune, 'X', tcell.ModShift), false},
+		{"X", tcell.NewEventKey(tcell.KeyRune, 'X', tcell.ModShift), true},
+		{"?", tcell.NewEventKey(tcell.KeyRune, '?', tcell.ModShift), true},
+		{"x", tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModAlt), false},
+		{"Alt-x", tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModAlt), true},
+		{"Alt-x", tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone), false},
+		{"Space", tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone), true},
+	}
+	for _, tt := range tests {
+		spec, err := parseKey(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := spec.matches(tt.ev); got != tt.want {
+			t.Errorf("%s matches %s: %t, want %t", tt.spec, tt.ev.Name(), got, tt.want)
+		}
+	}
+}
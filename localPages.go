@@ -5,6 +5,8 @@ import (
 	"github.com/gdamore/tcell/v2"
 	pb "github.com/rendicott/uggly"
 	"github.com/rendicott/uggo"
+	"strconv"
+	"strings"
 )
 
 func buildFeedBrowser(width int, keyStrokes []*pb.KeyStroke) *pb.PageResponse {
@@ -29,8 +31,12 @@ func buildFeedBrowser(width int, keyStrokes []*pb.KeyStroke) *pb.PageResponse {
 	for _, k := range keyStrokes {
 		switch x := k.Action.(type) {
 		case *pb.KeyStroke_Link:
-			contentString += fmt.Sprintf(
-				"(%s) %s\n", k.KeyStroke, x.Link.PageName)
+			if k.KeyStroke == "" {
+				contentString += fmt.Sprintf("    %s\n", x.Link.PageName)
+			} else {
+				contentString += fmt.Sprintf(
+					"(%s) %s\n", k.KeyStroke, x.Link.PageName)
+			}
 			localPage.KeyStrokes = append(localPage.KeyStrokes, k)
 		}
 	}
@@ -44,8 +50,113 @@ func buildFeedBrowser(width int, keyStrokes []*pb.KeyStroke) *pb.PageResponse {
 	return &localPage
 }
 
-func buildBookmarks(width, height int, s *ugglyBrowserSettings) *pb.PageResponse {
+// buildStartPage is the local page shown on startup and by the Home key
+// when there's no home page. It links the home page, the most recently
+// visited pages and the most recently visited bookmarks.
+func buildStartPage(width, height int, history []*historyEntry, bookmarks []*BookMark,
+	homePage, infoMsg string, actionKey func(string) string) *pb.PageResponse {
 	theme := genMenuTheme()
+	localPage := &pb.PageResponse{
+		Name:     "uggcli-start",
+		DivBoxes: &pb.DivBoxes{},
+		Elements: &pb.Elements{},
+	}
+	divStartX := uggo.Percent(10, width)
+	divStartY := uggo.Percent(10, height)
+	divWidth := int32(width) - (2 * divStartX)
+	divHeight := int32(height) - (2 * divStartY)
+	divName := "start-outer"
+	localPage.DivBoxes.Boxes = append(localPage.DivBoxes.Boxes,
+		theme.StylizeDivBox(&pb.DivBox{
+			Name:   divName,
+			Border: true,
+			StartX: divStartX,
+			StartY: divStartY,
+			Width:  divWidth,
+			Height: divHeight,
+		}))
+	// split what fits between the two lists, one line per entry
+	perList := (int(divHeight) - 12) / 2
+	if perList < 1 {
+		perList = 1
+	}
+	strokeIndex := 0
+	addLink := func(ugri string) string {
+		link, err := linkFromString(ugri)
+		if err != nil || strokeIndex > len(uggo.StrokeMap)-1 {
+			return "-"
+		}
+		stroke := uggo.StrokeMap[strokeIndex]
+		strokeIndex++
+		localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
+			KeyStroke: stroke,
+			Action: &pb.KeyStroke_Link{
+				Link: link,
+			}})
+		return stroke
+	}
+	msg := fmt.Sprintf("Start Page - enter an address with %s or hit a key below\n\n",
+		actionKey(actionAddress))
+	if infoMsg != "" {
+		msg += fmt.Sprintf("%s\n\n", infoMsg)
+	}
+	if homePage != "" {
+		msg += fmt.Sprintf("(%s) -- Home: %s\n\n", addLink(homePage), homePage)
+	}
+	msg += "Recently visited:\n"
+	if len(history) == 0 {
+		msg += "    nothing yet\n"
+	}
+	for i, h := range history {
+		if i >= perList {
+			break
+		}
+		name := h.Name
+		if name == "" {
+			name = h.Ugri
+		}
+		msg += fmt.Sprintf("(%s) -- %s: %s\n", addLink(h.Ugri), name, h.Ugri)
+	}
+	msg += "\nBookmarks:\n"
+	if len(bookmarks) == 0 {
+		msg += fmt.Sprintf("    none yet, add one with %s\n", actionKey(actionAddBookmark))
+	}
+	for i, bm := range bookmarks {
+		if i >= perList {
+			msg += fmt.Sprintf("    ...more with %s\n", actionKey(actionBookmarks))
+			break
+		}
+		msg += fmt.Sprintf("(%s) -- %s: %s\n", addLink(*bm.Ugri), *bm.ShortName, *bm.Ugri)
+	}
+	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs,
+		theme.StylizeTextBlob(&pb.TextBlob{
+			Content:  msg,
+			Wrap:     true,
+			DivNames: []string{divName},
+		}))
+	return localPage
+}
+
+// bookmarksPerPage is how many folders and bookmarks fit on the
+// bookmarks page, each one takes three lines
+func bookmarksPerPage(height int) int {
+	divHeight := int32(height) - (2 * uggo.Percent(15, height))
+	perPage := int(divHeight-10) / 3
+	if perPage < 1 {
+		perPage = 1
+	}
+	if perPage > len(uggo.StrokeMap) {
+		perPage = len(uggo.StrokeMap)
+	}
+	return perPage
+}
+
+// buildBookmarks shows one page of the entries for the folder being
+// browsed, or of the search results when there's a query, with a
+// search form and keystrokes to change folder and page
+func buildBookmarks(width, height int, entries []bookmarkEntry, folder, query string, offset int) *pb.PageResponse {
+	theme := genMenuTheme()
+	localAuthUuid = uggo.NewUuid() // we can ref this to trust links from this page
 	localPage := &pb.PageResponse{
 		Name:     "uggcli-bookmarks",
 		DivBoxes: &pb.DivBoxes{},
@@ -65,14 +176,75 @@ func buildBookmarks(width, height int, s *ugglyBrowserSettings) *pb.PageResponse
 			Width:  divWidth,
 			Height: divHeight,
 		}))
-	msg := fmt.Sprintf("Bookmarks Browser\n\n")
-	for i, bm := range s.Bookmarks {
-		if i > len(uggo.StrokeMap)-1 {
-			break
+	searchStroke := "/"
+	upStroke := "^"
+	prevStroke := "<"
+	nextStroke := ">"
+	formName := fmt.Sprintf("uggcli-bookmarksearch-%s", localAuthUuid)
+	localPage.Elements.Forms = append(localPage.Elements.Forms, &pb.Form{
+		Name:    formName,
+		DivName: divName,
+		SubmitLink: &pb.Link{
+			PageName: "uggcli-bookmarksearch",
+		},
+		TextBoxes: []*pb.TextBox{
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            "Query",
+				TabOrder:        1,
+				Description:     "Search",
+				DefaultValue:    query,
+				PositionX:       10,
+				PositionY:       3,
+				Height:          1,
+				Width:           uggo.Percent(50, int(divWidth)),
+				ShowDescription: true}),
+		}})
+	localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
+		KeyStroke: searchStroke,
+		Action: &pb.KeyStroke_FormActivation{
+			FormActivation: &pb.FormActivation{
+				FormName: formName,
+			}}})
+	addLink := func(stroke, pageName string) {
+		localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
+			KeyStroke: stroke,
+			Action: &pb.KeyStroke_Link{
+				Link: &pb.Link{
+					PageName: fmt.Sprintf("%s_%s", pageName, localAuthUuid),
+				},
+			}})
+	}
+	help := fmt.Sprintf("(%s) search", searchStroke)
+	location := fmt.Sprintf("Folder: /%s", folder)
+	if query != "" {
+		location = fmt.Sprintf("Search: '%s' (%d matches, blank search to browse)",
+			query, len(entries))
+	} else if folder != "" {
+		help += fmt.Sprintf("  (%s) up a folder", upStroke)
+		addLink(upStroke, "bookmarks_up")
+	}
+	perPage := bookmarksPerPage(height)
+	if offset > 0 {
+		help += fmt.Sprintf("  (%s) previous page", prevStroke)
+		addLink(prevStroke, "bookmarks_prev")
+	}
+	if offset+perPage < len(entries) {
+		help += fmt.Sprintf("  (%s) next page", nextStroke)
+		addLink(nextStroke, "bookmarks_next")
+	}
+	msg := fmt.Sprintf("Bookmarks Browser - %s\n%s\n\n\n\n", help, location)
+	for i := offset; i < len(entries) && i < offset+perPage; i++ {
+		stroke := uggo.StrokeMap[i-offset]
+		entry := entries[i]
+		if entry.bm == nil {
+			msg += fmt.Sprintf("(%s) -- [%s/]\n\n\n",
+				stroke, strings.TrimPrefix(entry.folder, folder+"/"))
+			addLink(stroke, fmt.Sprintf("bookmarks_folder_%d", i))
+			continue
 		}
-		stroke := uggo.StrokeMap[i]
-		msg += fmt.Sprintf("(%s) -- %s: %s\n\n",
-			stroke, *bm.ShortName, *bm.Ugri)
+		bm := entry.bm
+		msg += fmt.Sprintf("(%s) -- %s: %s\n       %s\n\n",
+			stroke, *bm.ShortName, *bm.Ugri, bm.details(query != ""))
 		link, err := linkFromString(*bm.Ugri)
 		if err != nil {
 			loggo.Debug("error generating Link from bookmark",
@@ -85,6 +257,12 @@ func buildBookmarks(width, height int, s *ugglyBrowserSettings) *pb.PageResponse
 				Link: link,
 			}})
 	}
+	if len(entries) == 0 {
+		msg += "nothing here\n"
+	} else if len(entries) > perPage {
+		msg += fmt.Sprintf("page %d of %d\n",
+			offset/perPage+1, (len(entries)+perPage-1)/perPage)
+	}
 	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs,
 		theme.StylizeTextBlob(&pb.TextBlob{
 			Content:  msg,
@@ -150,6 +328,83 @@ func buildSettings(width, height int, s *ugglyBrowserSettings, infoMsg string) *
 				Height:          1,
 				Width:           tbWidth,
 				ShowDescription: true}),
+
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            "AllowPasswordForms",
+				TabOrder:        3,
+				DefaultValue:    strings.Join(s.AllowPasswordForms, ","),
+				Description:     "Insecure pw forms ok",
+				PositionX:       tbPosX,
+				PositionY:       divStartY + 8,
+				Height:          1,
+				Width:           tbWidth,
+				ShowDescription: true}),
+
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            "DefaultCookiePolicy",
+				TabOrder:        4,
+				DefaultValue:    s.cookiePolicy(""),
+				Description:     "Default cookie policy",
+				PositionX:       tbPosX,
+				PositionY:       divStartY + 10,
+				Height:          1,
+				Width:           tbWidth,
+				ShowDescription: true}),
+
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            "CookiePolicies",
+				TabOrder:        5,
+				DefaultValue:    formatCookiePolicies(s.CookiePolicies),
+				Description:     "Cookie policies",
+				PositionX:       tbPosX,
+				PositionY:       divStartY + 12,
+				Height:          1,
+				Width:           tbWidth,
+				ShowDescription: true}),
+
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            "HomePage",
+				TabOrder:        6,
+				DefaultValue:    s.HomePage,
+				Description:     "Home page",
+				PositionX:       tbPosX,
+				PositionY:       divStartY + 14,
+				Height:          1,
+				Width:           tbWidth,
+				ShowDescription: true}),
+
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            "Startup",
+				TabOrder:        7,
+				DefaultValue:    s.Startup,
+				Description:     "On startup open",
+				PositionX:       tbPosX,
+				PositionY:       divStartY + 16,
+				Height:          1,
+				Width:           tbWidth,
+				ShowDescription: true}),
+
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            "RestoreForms",
+				TabOrder:        8,
+				DefaultValue:    strconv.FormatBool(s.RestoreForms),
+				Description:     "Restore form input",
+				PositionX:       tbPosX,
+				PositionY:       divStartY + 18,
+				Height:          1,
+				Width:           tbWidth,
+				ShowDescription: true}),
+
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            "Keymap",
+				TabOrder:        9,
+				DefaultValue:    formatKeymap(s.Keymap),
+				Description:     "Keymap action=key",
+				PositionX:       tbPosX,
+				PositionY:       divStartY + 20,
+				Height:          1,
+				Width:           tbWidth,
+				ShowDescription: true}),
 		}}
 	divCenter := divStartX + uggo.Percent(50, int(divWidth))
 	bmDivX := divStartX + divCenter
@@ -165,21 +420,33 @@ func buildSettings(width, height int, s *ugglyBrowserSettings, infoMsg string) *
 		Height: bmDivHeight,
 	})
 	localPage.DivBoxes.Boxes = append(localPage.DivBoxes.Boxes, bmDiv)
-	tabOrder := int32(3)
-	bmTbWidthSn := uggo.Percent(20, int(bmDivWidth))
-	bmTbWidthUg := uggo.Percent(65, int(bmDivWidth))
+	tabOrder := int32(10)
+	bmTbWidthSn := uggo.Percent(18, int(bmDivWidth))
+	bmTbWidthFo := uggo.Percent(15, int(bmDivWidth))
+	bmTbWidthTa := uggo.Percent(15, int(bmDivWidth))
+	bmTbWidthUg := uggo.Percent(35, int(bmDivWidth))
 	tbPosX1 := divCenter + 2
-	tbPosX2 := tbPosX1 + bmTbWidthSn + 3
+	tbPosX3 := tbPosX1 + bmTbWidthSn + 2
+	tbPosX4 := tbPosX3 + bmTbWidthFo + 2
+	tbPosX2 := tbPosX4 + bmTbWidthTa + 2
 	tbPosY2 := divStartY + 2
 	colShort := "Short Name"
+	colFolder := "Folder"
+	colTags := "Tags"
 	colUgri := "UGRI"
 	colDel := "del\n\n"
 	colShortX := int(tbPosX1 + divStartX)
 	colY := int(tbPosY2+divStartY) + 1
+	colFolderX := int(tbPosX3 + divStartX)
+	colTagsX := int(tbPosX4 + divStartX)
 	colUgriX := int(tbPosX2 + divStartX)
 	colDelX := int(colUgriX) + int(bmTbWidthUg) + 1
 	localPage = uggo.AddTextAt(
 		localPage, colShortX, colY, len(colShort), 1, colShort)
+	localPage = uggo.AddTextAt(
+		localPage, colFolderX, colY, len(colFolder), 1, colFolder)
+	localPage = uggo.AddTextAt(
+		localPage, colTagsX, colY, len(colTags), 1, colTags)
 	localPage = uggo.AddTextAt(
 		localPage, colUgriX, colY, len(colUgri), 1, colUgri)
 	for i, bm := range s.Bookmarks {
@@ -189,6 +456,8 @@ func buildSettings(width, height int, s *ugglyBrowserSettings, infoMsg string) *
 		tbPosY2 += 2
 		bmNameUgri := fmt.Sprintf("bookmark_ugri_%d", *bm.uid)
 		bmNameShortName := fmt.Sprintf("bookmark_shortname_%d", *bm.uid)
+		bmNameFolder := fmt.Sprintf("bookmark_folder_%d", *bm.uid)
+		bmNameTags := fmt.Sprintf("bookmark_tags_%d", *bm.uid)
 		loggo.Debug("adding bookmark to settings form",
 			"bm.Ugri", bm.Ugri,
 			"i", i,
@@ -205,6 +474,28 @@ func buildSettings(width, height int, s *ugglyBrowserSettings, infoMsg string) *
 				Width:           bmTbWidthSn,
 				ShowDescription: false}))
 		tabOrder++
+		settingsForm.TextBoxes = append(settingsForm.TextBoxes,
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            bmNameFolder,
+				TabOrder:        tabOrder,
+				DefaultValue:    bm.Folder,
+				PositionX:       tbPosX3,
+				PositionY:       tbPosY2,
+				Height:          1,
+				Width:           bmTbWidthFo,
+				ShowDescription: false}))
+		tabOrder++
+		settingsForm.TextBoxes = append(settingsForm.TextBoxes,
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            bmNameTags,
+				TabOrder:        tabOrder,
+				DefaultValue:    strings.Join(bm.Tags, ","),
+				PositionX:       tbPosX4,
+				PositionY:       tbPosY2,
+				Height:          1,
+				Width:           bmTbWidthTa,
+				ShowDescription: false}))
+		tabOrder++
 		settingsForm.TextBoxes = append(settingsForm.TextBoxes,
 			theme.StylizeTextBox(&pb.TextBox{
 				Name:            bmNameUgri,
@@ -254,6 +545,494 @@ func buildSettings(width, height int, s *ugglyBrowserSettings, infoMsg string) *
 			DivNames: []string{"bookmarks"},
 		}))
 	bmDiv.FillSt = uggo.Style("black", "cornsilk")
+	// bookmark export and import sit at the bottom of the left column
+	// on strokes that can't clash with the bookmark delete strokes
+	transferDivName := "bookmarks-transfer"
+	transferHeight := int32(8)
+	transferWidth := divCenter - 4
+	localPage.DivBoxes.Boxes = append(localPage.DivBoxes.Boxes,
+		theme.StylizeDivBox(&pb.DivBox{
+			Name:   transferDivName,
+			Border: true,
+			StartX: divStartX + 2,
+			StartY: divStartY + divHeight - transferHeight - 1,
+			Width:  transferWidth,
+			Height: transferHeight,
+		}))
+	transferTbWidth := uggo.Percent(30, int(transferWidth))
+	transferForm := func(name, stroke, desc string, row int32) {
+		formName := fmt.Sprintf("%s-%s", name, localAuthUuid)
+		localPage.Elements.Forms = append(localPage.Elements.Forms, &pb.Form{
+			Name:    formName,
+			DivName: transferDivName,
+			SubmitLink: &pb.Link{
+				PageName: name,
+			},
+			TextBoxes: []*pb.TextBox{
+				theme.StylizeTextBox(&pb.TextBox{
+					Name:            "BookmarkFile",
+					TabOrder:        1,
+					DefaultValue:    "bookmarks.yml",
+					Description:     desc,
+					PositionX:       14,
+					PositionY:       row,
+					Height:          1,
+					Width:           transferTbWidth,
+					ShowDescription: true}),
+				theme.StylizeTextBox(&pb.TextBox{
+					Name:            "BookmarkFolder",
+					TabOrder:        2,
+					Description:     "folder",
+					PositionX:       14 + transferTbWidth + 9,
+					PositionY:       row,
+					Height:          1,
+					Width:           transferTbWidth,
+					ShowDescription: true}),
+			}})
+		localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
+			KeyStroke: stroke,
+			Action: &pb.KeyStroke_FormActivation{
+				FormActivation: &pb.FormActivation{
+					FormName: formName,
+				}}})
+	}
+	exportStroke := ">"
+	importStroke := "<"
+	transferForm("uggcli-bookmarkexport", exportStroke, "Export to", 3)
+	transferForm("uggcli-bookmarkimport", importStroke, "Import from", 5)
+	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs,
+		theme.StylizeTextBlob(&pb.TextBlob{
+			Content: fmt.Sprintf("Hit (%s) to export or (%s) to import bookmarks "+
+				"as .yml, .json or .html (Netscape). Blank folder means all bookmarks.",
+				exportStroke, importStroke),
+			Wrap:     true,
+			DivNames: []string{transferDivName},
+		}))
+	return localPage
+}
+
+func buildCredentials(width, height int, creds []*credential, infoMsg string) *pb.PageResponse {
+	theme := genMenuTheme()
+	localAuthUuid = uggo.NewUuid() // we can ref this to trust links from this page
+	localPage := &pb.PageResponse{
+		Name:     "uggcli-credentials",
+		DivBoxes: &pb.DivBoxes{},
+		Elements: &pb.Elements{},
+	}
+	divStartX := uggo.Percent(10, width)
+	divStartY := uggo.Percent(10, height)
+	divWidth := int32(width) - (2 * divStartX)
+	divHeight := int32(height) - (2 * divStartY)
+	divName := "credentials-outer"
+	localPage.DivBoxes.Boxes = append(localPage.DivBoxes.Boxes,
+		theme.StylizeDivBox(&pb.DivBox{
+			Name:   divName,
+			Border: true,
+			StartX: divStartX,
+			StartY: divStartY,
+			Width:  divWidth,
+			Height: divHeight,
+		}))
+	msg := "Saved Logins - hit a key to delete the login\n\n"
+	if infoMsg != "" {
+		msg += fmt.Sprintf("%s\n\n", infoMsg)
+	}
+	if len(creds) == 0 {
+		msg += "no saved logins"
+	}
+	for i, c := range creds {
+		if i > len(uggo.StrokeMap)-1 {
+			break
+		}
+		stroke := uggo.StrokeMap[i]
+		fields := "never save"
+		if !c.Never {
+			values := []string{}
+			for k, v := range c.Values {
+				for _, pw := range c.Passwords {
+					if pw == k {
+						v = "********"
+					}
+				}
+				values = append(values, fmt.Sprintf("%s=%s", k, v))
+			}
+			fields = strings.Join(values, ", ")
+		}
+		msg += fmt.Sprintf("(%s) -- %s form '%s': %s (saved %s)\n\n",
+			stroke, c.Server, c.Form, fields, c.Saved)
+		delPage := fmt.Sprintf("credential_delete_%d_%s", i, localAuthUuid)
+		localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
+			KeyStroke: stroke,
+			Action: &pb.KeyStroke_Link{
+				Link: &pb.Link{PageName: delPage},
+			}})
+	}
+	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs,
+		theme.StylizeTextBlob(&pb.TextBlob{
+			Content:  msg,
+			Wrap:     true,
+			DivNames: []string{divName},
+		}))
+	return localPage
+}
+
+// buildProfiles lists the profiles with keystrokes to switch to each
+// one and a form to create a new profile
+func buildProfiles(width, height int, profiles []string, current, infoMsg string) *pb.PageResponse {
+	theme := genMenuTheme()
+	localAuthUuid = uggo.NewUuid() // we can ref this to trust links from this page
+	localPage := &pb.PageResponse{
+		Name:     "uggcli-profiles",
+		DivBoxes: &pb.DivBoxes{},
+		Elements: &pb.Elements{},
+	}
+	divStartX := uggo.Percent(10, width)
+	divStartY := uggo.Percent(10, height)
+	divWidth := int32(width) - (2 * divStartX)
+	divHeight := int32(height) - (2 * divStartY)
+	divName := "profiles-outer"
+	localPage.DivBoxes.Boxes = append(localPage.DivBoxes.Boxes,
+		theme.StylizeDivBox(&pb.DivBox{
+			Name:   divName,
+			Border: true,
+			StartX: divStartX,
+			StartY: divStartY,
+			Width:  divWidth,
+			Height: divHeight,
+		}))
+	newStroke := uggo.StrokeMap[0]
+	formName := fmt.Sprintf("uggcli-profilenew-%s", localAuthUuid)
+	localPage.Elements.Forms = append(localPage.Elements.Forms, &pb.Form{
+		Name:    formName,
+		DivName: divName,
+		SubmitLink: &pb.Link{
+			PageName: "uggcli-profilenew",
+		},
+		TextBoxes: []*pb.TextBox{
+			theme.StylizeTextBox(&pb.TextBox{
+				Name:            "ProfileName",
+				TabOrder:        1,
+				Description:     "New profile",
+				PositionX:       16,
+				PositionY:       3,
+				Height:          1,
+				Width:           uggo.Percent(30, int(divWidth)),
+				ShowDescription: true}),
+		}})
+	localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
+		KeyStroke: newStroke,
+		Action: &pb.KeyStroke_FormActivation{
+			FormActivation: &pb.FormActivation{
+				FormName: formName,
+			}}})
+	msg := fmt.Sprintf("Profiles - hit a key to switch or (%s) to create a new one\n\n\n\n",
+		newStroke)
+	if infoMsg != "" {
+		msg += fmt.Sprintf("%s\n\n", infoMsg)
+	}
+	for i, p := range profiles {
+		if i+1 > len(uggo.StrokeMap)-1 {
+			break
+		}
+		stroke := uggo.StrokeMap[i+1]
+		marker := ""
+		if p == current {
+			marker = " (current)"
+		}
+		msg += fmt.Sprintf("(%s) -- %s%s\n\n", stroke, profileName(p), marker)
+		localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
+			KeyStroke: stroke,
+			Action: &pb.KeyStroke_Link{
+				Link: &pb.Link{
+					PageName: fmt.Sprintf("profile_switch_%d_%s", i, localAuthUuid),
+				},
+			}})
+	}
+	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs,
+		theme.StylizeTextBlob(&pb.TextBlob{
+			Content:  msg,
+			Wrap:     true,
+			DivNames: []string{divName},
+		}))
+	return localPage
+}
+
+// buildCookies lists every cookie the browser holds grouped by server
+// with keystrokes to delete a single cookie, clear a server or clear
+// everything. Links carry the localAuthUuid so a server can't forge them.
+func buildCookies(width, height int, servers []string, cookies map[string][]*pb.Cookie,
+	pending []string, s *ugglyBrowserSettings, infoMsg string) *pb.PageResponse {
+	theme := genMenuTheme()
+	localAuthUuid = uggo.NewUuid() // we can ref this to trust links from this page
+	localPage := &pb.PageResponse{
+		Name:     "uggcli-cookies",
+		DivBoxes: &pb.DivBoxes{},
+		Elements: &pb.Elements{},
+	}
+	divStartX := uggo.Percent(5, width)
+	divStartY := uggo.Percent(5, height)
+	divWidth := int32(width) - (2 * divStartX)
+	divHeight := int32(height) - (2 * divStartY)
+	divName := "cookies-outer"
+	transferDivName := "cookies-transfer"
+	transferHeight := int32(9)
+	localPage.DivBoxes.Boxes = append(localPage.DivBoxes.Boxes,
+		theme.StylizeDivBox(&pb.DivBox{
+			Name:   transferDivName,
+			Border: true,
+			StartX: divStartX,
+			StartY: divStartY,
+			Width:  divWidth,
+			Height: transferHeight,
+		}),
+		theme.StylizeDivBox(&pb.DivBox{
+			Name:   divName,
+			Border: true,
+			StartX: divStartX,
+			StartY: divStartY + transferHeight,
+			Width:  divWidth,
+			Height: divHeight - transferHeight,
+		}))
+	strokeIndex := 0
+	// addAction binds the next available stroke to a local link
+	addAction := func(pageName string) string {
+		if strokeIndex > len(uggo.StrokeMap)-1 {
+			return "-"
+		}
+		stroke := uggo.StrokeMap[strokeIndex]
+		strokeIndex++
+		localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
+			KeyStroke: stroke,
+			Action: &pb.KeyStroke_Link{
+				Link: &pb.Link{
+					PageName: fmt.Sprintf("%s_%s", pageName, localAuthUuid),
+				},
+			}})
+		return stroke
+	}
+	// export and import forms share a layout and take the first strokes
+	tbWidth := uggo.Percent(30, int(divWidth))
+	transferForm := func(name, desc string, row int32) string {
+		if strokeIndex > len(uggo.StrokeMap)-1 {
+			return "-"
+		}
+		formName := fmt.Sprintf("%s-%s", name, localAuthUuid)
+		localPage.Elements.Forms = append(localPage.Elements.Forms, &pb.Form{
+			Name:    formName,
+			DivName: transferDivName,
+			SubmitLink: &pb.Link{
+				PageName: name,
+			},
+			TextBoxes: []*pb.TextBox{
+				theme.StylizeTextBox(&pb.TextBox{
+					Name:            "CookieFile",
+					TabOrder:        1,
+					DefaultValue:    "cookies-export.json",
+					Description:     desc,
+					PositionX:       24,
+					PositionY:       row,
+					Height:          1,
+					Width:           tbWidth,
+					ShowDescription: true}),
+				theme.StylizeTextBox(&pb.TextBox{
+					Name:            "CookieServers",
+					TabOrder:        2,
+					Description:     "servers",
+					PositionX:       24 + tbWidth + 10,
+					PositionY:       row,
+					Height:          1,
+					Width:           tbWidth,
+					ShowDescription: true}),
+			}})
+		stroke := uggo.StrokeMap[strokeIndex]
+		strokeIndex++
+		localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
+			KeyStroke: stroke,
+			Action: &pb.KeyStroke_FormActivation{
+				FormActivation: &pb.FormActivation{
+					FormName: formName,
+				}}})
+		return stroke
+	}
+	exportStroke := transferForm("uggcli-cookieexport", "Export to file", 3)
+	importStroke := transferForm("uggcli-cookieimport", "Import from file", 5)
+	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs,
+		theme.StylizeTextBlob(&pb.TextBlob{
+			Content: fmt.Sprintf("Hit (%s) to export or (%s) to import cookies, "+
+				"then Enter to submit. Blank servers means all servers.\n"+
+				"Exports are NOT encrypted, only persistent cookies are exported.",
+				exportStroke, importStroke),
+			Wrap:     true,
+			DivNames: []string{transferDivName},
+		}))
+	msg := "Cookie Manager\n\n"
+	if infoMsg != "" {
+		msg += fmt.Sprintf("%s\n\n", infoMsg)
+	}
+	for i, site := range pending {
+		msg += fmt.Sprintf("'%s' wants to set cookies: ", site)
+		for _, policy := range []string{cookiePolicyAllow, cookiePolicySession, cookiePolicyBlock} {
+			msg += fmt.Sprintf("(%s) %s  ", addAction(
+				fmt.Sprintf("cookie_policy_%d_%s", i, policy)), policy)
+		}
+		msg += "\n\n"
+	}
+	if len(servers) == 0 {
+		msg += "no cookies stored"
+	} else {
+		msg += fmt.Sprintf("(%s) clear all cookies\n\n", addAction("cookie_clearall"))
+	}
+	for i, server := range servers {
+		msg += fmt.Sprintf("(%s) clear server '%s' (policy %s)\n",
+			addAction(fmt.Sprintf("cookie_clearserver_%d", i)), server,
+			s.serverCookiePolicy(server))
+		for j, c := range cookies[server] {
+			expires := c.Expires
+			if expires == "" {
+				expires = "session"
+			}
+			page := c.Page
+			if page == "" {
+				page = "*"
+			}
+			msg += fmt.Sprintf("  (%s) %s  expires=%s sameSite=%s secure=%t "+
+				"private=%t metadata=%t page=%s\n",
+				addAction(fmt.Sprintf("cookie_delete_%d_%d", i, j)),
+				c.Key, expires, c.SameSite.String(), c.Secure,
+				c.Private, c.Metadata, page)
+		}
+		msg += "\n"
+	}
+	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs,
+		theme.StylizeTextBlob(&pb.TextBlob{
+			Content:  msg,
+			Wrap:     true,
+			DivNames: []string{divName},
+		}))
+	return localPage
+}
+
+// modalMinWidth keeps modals readable on narrow terminals
+const modalMinWidth = 20
+
+// buildModal generates a bordered box centered on the screen which
+// is drawn over the current content to ask the user a question.
+// Width and height are the full screen's, menu included.
+func buildModal(width, height int, title, msg string) *pb.PageResponse {
+	theme := genMenuTheme()
+	localPage := &pb.PageResponse{
+		Name:     "uggcli-modal",
+		DivBoxes: &pb.DivBoxes{},
+		Elements: &pb.Elements{},
+	}
+	content := fmt.Sprintf("%s\n\n%s", title, msg)
+	divWidth := uggo.Percent(60, width)
+	if divWidth < modalMinWidth {
+		divWidth = modalMinWidth
+	}
+	if divWidth > int32(width) {
+		divWidth = int32(width)
+	}
+	textWidth := int(divWidth) - 2
+	if textWidth < 1 {
+		textWidth = 1
+	}
+	// rough guess at the wrapped height plus border and padding
+	lines := strings.Count(content, "\n") + len(content)/textWidth + 4
+	divHeight := int32(lines)
+	if divHeight > int32(height) {
+		divHeight = int32(height)
+	}
+	divName := "uggcli-modal"
+	localPage.DivBoxes.Boxes = append(localPage.DivBoxes.Boxes,
+		theme.StylizeDivBox(&pb.DivBox{
+			Name:   divName,
+			Border: true,
+			StartX: (int32(width) - divWidth) / 2,
+			StartY: (int32(height) - divHeight) / 2,
+			Width:  divWidth,
+			Height: divHeight,
+		}))
+	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs,
+		theme.StylizeTextBlob(&pb.TextBlob{
+			Content:  content,
+			Wrap:     true,
+			DivNames: []string{divName},
+		}))
+	return localPage
+}
+
+// buildBookmarkForm is the modal shown by F7 to name, tag and file a
+// bookmark before it's saved, prefilled from bm
+func buildBookmarkForm(width, height int, bm *BookMark, exists bool) *pb.PageResponse {
+	theme := genMenuTheme()
+	localPage := &pb.PageResponse{
+		Name:     "uggcli-bookmarkadd",
+		DivBoxes: &pb.DivBoxes{},
+		Elements: &pb.Elements{},
+	}
+	divWidth := uggo.Percent(60, width)
+	if divWidth < modalMinWidth {
+		divWidth = modalMinWidth
+	}
+	if divWidth > int32(width) {
+		divWidth = int32(width)
+	}
+	divHeight := int32(14)
+	if divHeight > int32(height) {
+		divHeight = int32(height)
+	}
+	divName := "uggcli-modal"
+	localPage.DivBoxes.Boxes = append(localPage.DivBoxes.Boxes,
+		theme.StylizeDivBox(&pb.DivBox{
+			Name:   divName,
+			Border: true,
+			StartX: (int32(width) - divWidth) / 2,
+			StartY: (int32(height) - divHeight) / 2,
+			Width:  divWidth,
+			Height: divHeight,
+		}))
+	title := "Add bookmark"
+	if exists {
+		title = "Edit bookmark (this page is already bookmarked)"
+	}
+	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs,
+		theme.StylizeTextBlob(&pb.TextBlob{
+			Content: fmt.Sprintf("%s\n%s\n\n\n\n\n\n\n\n\n"+
+				"Tab between fields, Enter to save, Escape to cancel. "+
+				"Tags are comma separated, folders look like work/internal.",
+				title, *bm.Ugri),
+			Wrap:     true,
+			DivNames: []string{divName},
+		}))
+	tbWidth := divWidth - 18
+	if tbWidth < 1 {
+		tbWidth = 1
+	}
+	textBox := func(name, desc, value string, tabOrder, row int32) *pb.TextBox {
+		return theme.StylizeTextBox(&pb.TextBox{
+			Name:            name,
+			TabOrder:        tabOrder,
+			DefaultValue:    value,
+			Description:     desc,
+			PositionX:       14,
+			PositionY:       row,
+			Height:          1,
+			Width:           tbWidth,
+			ShowDescription: true})
+	}
+	localPage.Elements.Forms = append(localPage.Elements.Forms, &pb.Form{
+		Name:    "uggcli-bookmarkadd",
+		DivName: divName,
+		SubmitLink: &pb.Link{
+			PageName: "uggcli-bookmarkadd",
+		},
+		TextBoxes: []*pb.TextBox{
+			textBox("ShortName", "Name", *bm.ShortName, 1, 3),
+			textBox("Tags", "Tags", strings.Join(bm.Tags, ","), 2, 5),
+			textBox("Folder", "Folder", bm.Folder, 3, 7),
+			textBox("Description", "Description", bm.Description, 4, 9),
+		}})
 	return localPage
 }
 
@@ -307,7 +1086,8 @@ func buildStatus(message string, width, height int) *pb.PageResponse {
 // buildPageMenu takes some dimensions as input and generates an uggly.PageResponse
 // which can then be easily rendered back in the browser just like a server
 // response would be.
-func buildPageMenu(width, height int, server, port, page, msg string, secure bool) *pb.PageResponse {
+func buildPageMenu(width, height int, server, port, page, msg string, secure bool,
+	bindings []keyBinding) *pb.PageResponse {
 	// since we already have functions for converting to divboxes
 	// we'll just build a local pageResponse
 	localPage := pb.PageResponse{
@@ -345,22 +1125,24 @@ func buildPageMenu(width, height int, server, port, page, msg string, secure boo
 		Height:   int32(height) / 3,
 		FillSt:   uggo.Style("white", "white"),
 	})
-	menuText := fmt.Sprintf(
-		"uggcli-menu v%s === "+
-			"  ColorDemo (F2)"+
-			"  Settings (F3)"+
-			"  Browse Feed (F4)"+
-			"  Refresh (F5)"+
-			"  Bookmarks (F6)"+
-			"  AddBookmark (F7)"+
-			"  Exit (F10)",
-		version)
+	// menu text and keystrokes both come from the keymap
+	menuText := fmt.Sprintf("uggcli-menu v%s === ", version)
+	addressKey := "none"
+	for _, kb := range bindings {
+		if kb.name == actionAddress {
+			addressKey = kb.key.String()
+		}
+		if kb.label != "" {
+			menuText += fmt.Sprintf("  %s (%s)", kb.label, kb.key.String())
+		}
+	}
 	localPage.Elements.TextBlobs = append(localPage.Elements.TextBlobs, &pb.TextBlob{
 		Content:  menuText,
 		Wrap:     true,
 		Style:    uggo.Style("white", "black"),
 		DivNames: []string{"uggcli-menu"},
 	})
+	addressDescription := fmt.Sprintf("Host: (%s)", addressKey)
 	addressDescriptionColor := uggo.Style("white", "red")
 	addressPrefix := "ugtp://"
 	if secure {
@@ -375,8 +1157,8 @@ func buildPageMenu(width, height int, server, port, page, msg string, secure boo
 			TabOrder: int32(0),
 			DefaultValue: fmt.Sprintf(
 				"%s%s:%s/%s", addressPrefix, server, port, page),
-			Description:      "Host: (F1)",
-			PositionX:        int32(14),
+			Description:      addressDescription,
+			PositionX:        int32(len(addressDescription) + 4),
 			PositionY:        int32(0),
 			Height:           int32(1),
 			Width:            int32(width / 2),
@@ -404,44 +1186,7 @@ func buildPageMenu(width, height int, server, port, page, msg string, secure boo
 		Style:    uggo.Style("black", "white"),
 		DivNames: []string{"uggcli-statusbar"},
 	})
-	localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
-		KeyStroke: "F4",
-		Action: &pb.KeyStroke_Link{
-			Link: &pb.Link{
-				PageName: "FEEDBROWSER",
-				Server:   "MENU",
-				Port:     "0",
-			},
-		},
-	})
-	localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
-		KeyStroke: "F5",
-		Action: &pb.KeyStroke_Link{
-			Link: &pb.Link{
-				PageName: "REFRESH",
-				Server:   "MENU",
-				Port:     "0",
-			},
-		},
-	})
-	localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
-		KeyStroke: "F2",
-		Action: &pb.KeyStroke_Link{
-			Link: &pb.Link{
-				PageName: "COLORDEMO",
-				Server:   "MENU",
-				Port:     "0",
-			},
-		},
-	})
-	localPage.KeyStrokes = append(localPage.KeyStrokes, &pb.KeyStroke{
-		KeyStroke: "F1",
-		Action: &pb.KeyStroke_FormActivation{
-			FormActivation: &pb.FormActivation{
-				FormName: "address-bar",
-			},
-		},
-	})
+	localPage.KeyStrokes = append(localPage.KeyStrokes, menuKeyStrokes(bindings)...)
 	return &localPage
 }
 
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"github.com/gdamore/tcell/v2"
+	pb "github.com/rendicott/uggly"
+	"github.com/rendicott/uggly-client/ugcon"
+	"unicode"
+)
+
+// promptKey draws a modal box over the current content and waits for
+// one of keys to be pressed, Escape returns 0. It polls the screen
+// itself so it must only be called while nothing else is polling,
+// e.g., after a form handed control back but before passForm returns.
+func (b *ugglyBrowser) promptKey(title, msg string, keys []rune) rune {
+	page := buildModal(b.vW, b.vH+b.menuHeight, title, msg)
+	modal, err := convertPageBoxes(page)
+	if err != nil {
+		loggo.Error("error building modal", "err", err.Error())
+		return 0
+	}
+	b.drawBoxes(modal)
+	b.view.Show()
+	defer b.drawContent("modal-close")
+	for {
+		ev := b.view.PollEvent()
+		kev, ok := ev.(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		if kev.Key() == tcell.KeyEscape {
+			return 0
+		}
+		if kev.Key() != tcell.KeyRune {
+			continue
+		}
+		r := unicode.ToLower(kev.Rune())
+		for _, k := range keys {
+			if r == k {
+				loggo.Debug("got modal answer", "title", title, "key", string(k))
+				return k
+			}
+		}
+	}
+}
+
+// promptForm draws a modal page holding a single form over the current
+// content and hands control to the form. Returns the form's values and
+// true if it was submitted, false if the user escaped out of it. Like
+// promptKey it must only be called while nothing else is polling.
+func (b *ugglyBrowser) promptForm(page *pb.PageResponse) (map[string]string, bool) {
+	if page.Elements == nil || len(page.Elements.Forms) == 0 {
+		return nil, false
+	}
+	modal, err := convertPageBoxes(page)
+	if err != nil {
+		loggo.Error("error building modal", "err", err.Error())
+		return nil, false
+	}
+	form := page.Elements.Forms[0]
+	f, err := ugcon.ConvertFormLocalForm(form, b.view)
+	if err != nil {
+		loggo.Error("error building modal form", "err", err.Error())
+		return nil, false
+	}
+	for _, div := range page.DivBoxes.Boxes {
+		if form.DivName == div.Name {
+			f.ShiftXY(int(div.StartX), int(div.StartY+div.BorderW))
+		}
+	}
+	b.drawBoxes(modal)
+	f.Start()
+	b.view.Show()
+	defer b.drawContent("modal-close")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan struct{})
+	submit := make(chan string)
+	go f.Poll(ctx, interrupt, submit)
+	var values map[string]string
+	submitted := false
+	for {
+		select {
+		case <-submit:
+			// the form closes interrupt right after sending
+			values = f.Collect()
+			submitted = true
+		case <-interrupt:
+			loggo.Debug("modal form closed", "form", form.Name, "submitted", submitted)
+			return values, submitted
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// appDirName is the directory created under each of the XDG base
+// directories to hold the browser's files
+const appDirName = "ugglyc"
+
+// file names used before files moved to the XDG base directories.
+// They were relative so they ended up in whatever the CWD was.
+const (
+	legacyConfigFile = "config.yml"
+	legacyVaultFile  = "cookies.json.encrypted"
+	legacyLogFile    = "uggcli.log.json"
+)
+
+// xdgDir returns the browser's directory under the XDG base directory
+// in env or under fallback in the user's home when env isn't set. With
+// no home directory we're left with the CWD like in the old days.
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDirName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(home, fallback, appDirName)
+}
+
+// configDir holds config.yml
+func configDir() string {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// stateDir holds the cookie vault, saved logins and anything else the
+// browser needs to remember between runs
+func stateDir() string {
+	return xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// cacheDir holds files that can be thrown away like the log
+func cacheDir() string {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+func defaultConfigFile() string {
+	return filepath.Join(configDir(), legacyConfigFile)
+}
+
+func defaultVaultFile() string {
+	return filepath.Join(stateDir(), legacyVaultFile)
+}
+
+func defaultLogFile() string {
+	return filepath.Join(cacheDir(), legacyLogFile)
+}
+
+// migrateFile moves a file left in the CWD by an older version to its
+// new home unless something is already there. Returns a message for
+// the user if a file was moved.
+func migrateFile(legacy, dest string) (string, error) {
+	if _, err := os.Stat(dest); err == nil {
+		return "", nil
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return "", nil
+	}
+	err := os.MkdirAll(filepath.Dir(dest), 0700)
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(legacy, dest)
+	if err != nil {
+		// rename can't cross filesystems so copy instead
+		var dat []byte
+		dat, err = ioutil.ReadFile(legacy)
+		if err == nil {
+			err = ioutil.WriteFile(dest, dat, 0600)
+		}
+		if err == nil {
+			err = os.Remove(legacy)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("moved '%s' to '%s'", legacy, dest), nil
+}
+
+// browserPaths resolves where the config, vault and log live for a
+// profile. Flags that were given win, everything else defaults to the
+// XDG base directories and files found in the CWD from older versions
+// are moved to the default profile. The returned messages describe any
+// files moved.
+func browserPaths(profile, configFlag, vaultFlag, logFlag string) (config, vault, log string, msgs []string) {
+	type move struct{ legacy, dest string }
+	moves := []move{}
+	config, vault, log = configFlag, vaultFlag, logFlag
+	if config == "" {
+		config = profileConfigFile(profile)
+		if profile == "" {
+			moves = append(moves, move{legacyConfigFile, config})
+		}
+	}
+	if vault == "" {
+		vault = profileVaultFile(profile)
+	}
+	if vaultFlag == "" && profile == "" {
+		moves = append(moves,
+			move{legacyVaultFile, vault},
+			move{credentialFileName, filepath.Join(filepath.Dir(vault), credentialFileName)})
+	}
+	if log == "" {
+		log = defaultLogFile()
+	}
+	for _, m := range moves {
+		msg, err := migrateFile(m.legacy, m.dest)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("error moving '%s' to '%s': %s",
+				m.legacy, m.dest, err.Error()))
+		} else if msg != "" {
+			msgs = append(msgs, msg)
+		}
+	}
+	for _, f := range []string{config, vault, log} {
+		ensureDir(f)
+	}
+	return config, vault, log, msgs
+}
+
+// ensureDir creates the directory a file goes in. Some writers, like
+// the log handler, can't create directories themselves.
+func ensureDir(filename string) {
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil && loggo != nil {
+		loggo.Error("error creating directory", "filename", filename, "error", err.Error())
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+)
+
+// pinsFileName is stored next to the cookie vault so every profile
+// trusts its own set of server certificates
+const pinsFileName = "pins.json"
+
+var errPinMismatch = errors.New("certificate doesn't match the pinned one")
+
+// pinStore pins the certificate a secure server:port presents the first
+// time a profile connects to it and refuses a different one afterwards.
+// Pins are the SHA-256 of the certificate's public key so a renewed
+// certificate for the same key is still accepted.
+type pinStore struct {
+	mu       sync.Mutex
+	file     string
+	pins     map[string]string
+	rejected map[string]bool // server:port whose last handshake failed its pin
+}
+
+func newPinStore() *pinStore {
+	return &pinStore{
+		pins:     make(map[string]string),
+		rejected: make(map[string]bool),
+	}
+}
+
+func (b *ugglyBrowser) pinsFile() string {
+	return filepath.Join(filepath.Dir(*b.settings.VaultFile), pinsFileName)
+}
+
+// load replaces the pins with the ones stored in file, e.g., when
+// switching profile. A missing file just means nothing is pinned yet.
+func (p *pinStore) load(file string) (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.file = file
+	p.pins = make(map[string]string)
+	p.rejected = make(map[string]bool)
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(dat, &p.pins)
+	loggo.Info("loaded certificate pins", "file", file, "num_pins", len(p.pins))
+	return err
+}
+
+// save writes the pins to disk. Callers hold the lock.
+func (p *pinStore) save() error {
+	if p.file == "" {
+		return nil
+	}
+	dat, err := json.MarshalIndent(p.pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	ensureDir(p.file)
+	return ioutil.WriteFile(p.file, dat, 0600)
+}
+
+func pinOf(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:])
+}
+
+// verify returns a tls.Config.VerifyConnection for site which runs after
+// the usual certificate checks passed
+func (p *pinStore) verify(site string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("no certificate from %s", site)
+		}
+		return p.check(site, pinOf(cs.PeerCertificates[0]))
+	}
+}
+
+// check pins site to pin if it has no pin yet or compares it with the
+// stored one
+func (p *pinStore) check(site, pin string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pinned, ok := p.pins[site]
+	if !ok {
+		p.pins[site] = pin
+		loggo.Info("pinned certificate", "site", site, "pin", pin)
+		if err := p.save(); err != nil {
+			loggo.Error("error saving certificate pins", "error", err.Error())
+		}
+		return nil
+	}
+	if pinned != pin {
+		loggo.Error("certificate changed since it was pinned",
+			"site", site, "pinned", pinned, "got", pin)
+		p.rejected[site] = true
+		return errPinMismatch
+	}
+	delete(p.rejected, site)
+	return nil
+}
+
+// wasRejected reports, and forgets, whether a handshake with site
+// failed because of its pin. Dial errors don't carry the handshake's.
+func (p *pinStore) wasRejected(site string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rejected := p.rejected[site]
+	delete(p.rejected, site)
+	return rejected
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPinStoreCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		pins         []string // pins presented in order, the last one is checked
+		wantErr      bool
+		wantRejected bool
+	}{
+		{"first use pins", []string{"a"}, false, false},
+		{"same key", []string{"a", "a"}, false, false},
+		{"changed key", []string{"a", "b"}, true, true},
+		{"changed key stays refused", []string{"a", "b", "b"}, true, true},
+		{"original key after a refusal", []string{"a", "b", "a"}, false, false},
+	}
+	for _, tt := range tests {
+		p := newPinStore()
+		var err error
+		for _, pin := range tt.pins {
+			err = p.check("server:4443", pin)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err %v, want error %t", tt.name, err, tt.wantErr)
+		}
+		if got := p.wasRejected("server:4443"); got != tt.wantRejected {
+			t.Errorf("%s: rejected %t, want %t", tt.name, got, tt.wantRejected)
+		}
+		if p.wasRejected("server:4443") {
+			t.Errorf("%s: rejection should only be reported once", tt.name)
+		}
+	}
+}
+
+func TestPinStorePerSite(t *testing.T) {
+	p := newPinStore()
+	p.check("server:4443", "a")
+	if err := p.check("server:5443", "b"); err != nil {
+		t.Errorf("another port of the same server has its own pin: %v", err)
+	}
+}
+
+func TestPinStoreLoad(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work", pinsFileName)
+	home := filepath.Join(dir, "home", pinsFileName)
+	p := newPinStore()
+	if err := p.load(work); err == nil {
+		t.Errorf("loading a missing file should say so")
+	}
+	p.check("server:4443", "a")
+	// another profile knows nothing of the first one's pins
+	p.load(home)
+	if err := p.check("server:4443", "b"); err != nil {
+		t.Errorf("pin leaked into another profile: %v", err)
+	}
+	if err := p.load(work); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.check("server:4443", "b"); err == nil {
+		t.Errorf("pin wasn't saved with the profile")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// profilesDirName holds one directory per named profile under both the
+// config and state directories. The default profile, named "", keeps
+// using the files directly in those directories.
+const profilesDirName = "profiles"
+
+var profileNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func validProfileName(name string) bool {
+	return name == "" || profileNameRegex.MatchString(name)
+}
+
+// profileName is how a profile is shown to the user
+func profileName(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+func profileConfigFile(name string) string {
+	if name == "" {
+		return defaultConfigFile()
+	}
+	return filepath.Join(configDir(), profilesDirName, name, legacyConfigFile)
+}
+
+func profileVaultFile(name string) string {
+	if name == "" {
+		return defaultVaultFile()
+	}
+	return filepath.Join(stateDir(), profilesDirName, name, legacyVaultFile)
+}
+
+// listProfiles returns the default profile followed by every named
+// profile that has a config directory
+func listProfiles() []string {
+	profiles := []string{}
+	entries, err := ioutil.ReadDir(filepath.Join(configDir(), profilesDirName))
+	if err == nil {
+		for _, e := range entries {
+			if e.IsDir() && validProfileName(e.Name()) {
+				profiles = append(profiles, e.Name())
+			}
+		}
+	}
+	sort.Strings(profiles)
+	return append([]string{""}, profiles...)
+}
+
+// switchProfile saves everything belonging to the current profile and
+// loads the settings, bookmarks, cookies, saved logins, history and
+// certificate pins of another one, creating it if it doesn't exist yet
+func (b *ugglyBrowser) switchProfile(name string) (err error) {
+	if !validProfileName(name) {
+		return fmt.Errorf("profile names can only use letters, numbers, '-' and '_'")
+	}
+	err = b.storeCookies()
+	if err != nil {
+		loggo.Error("error storing cookies before switching profile", "error", err.Error())
+	}
+	err = b.storeSession(true)
+	if err != nil {
+		loggo.Error("error storing session before switching profile", "error", err.Error())
+	}
+	err = b.settingsSave()
+	if err != nil {
+		return err
+	}
+	// hold the save lock so a background cookie save can't write
+	// this profile's jar into the next profile's vault
+	b.cookieSaveMu.Lock()
+	// and the settings lock so the watcher doesn't reload the old file
+	b.settingsMu.Lock()
+	b.profile = name
+	b.settingsFile = profileConfigFile(name)
+	envVar := b.settings.VaultPassEnvVar
+	*b.settings = *b.settingsLoad()
+	if _, err := os.Stat(b.settingsFile); os.IsNotExist(err) {
+		// new profiles keep the vault password ENV var in use
+		b.settings.VaultPassEnvVar = envVar
+	}
+	b.settingsMu.Unlock()
+	b.cookies.Clear()
+	b.pendingMu.Lock()
+	b.pendingCookies = make(map[string]*heldCookies)
+	b.pendingMu.Unlock()
+	b.savedCookies = ""
+	b.credentials = make([]*credential, 0)
+	b.history = make([]*historyEntry, 0)
+	b.autofillDeclined = make(map[string]bool)
+	b.formState = newFormState()
+	b.cookieSaveMu.Unlock()
+	ensureDir(b.settingsFile)
+	ensureDir(*b.settings.VaultFile)
+	err = b.settingsSave()
+	if err != nil {
+		return err
+	}
+	if err := b.loadCookies(); err != nil {
+		loggo.Info("no cookies loaded for profile", "profile", profileName(name), "error", err.Error())
+	}
+	if err := b.loadCredentials(); err != nil {
+		loggo.Info("no saved logins loaded for profile", "profile", profileName(name), "error", err.Error())
+	}
+	if err := b.loadHistory(); err != nil {
+		loggo.Info("no history loaded for profile", "profile", profileName(name), "error", err.Error())
+	}
+	if err := b.pins.load(b.pinsFile()); err != nil {
+		loggo.Info("no certificate pins loaded for profile", "profile", profileName(name), "error", err.Error())
+	}
+	// a connection made under the old profile was checked against its pins
+	b.sess.close()
+	loggo.Info("switched profile", "profile", profileName(name), "settingsFile", b.settingsFile)
+	return nil
+}
+
+func (b *ugglyBrowser) profilesPage(infoMsg string) {
+	thisfunc := "profilesPage"
+	loggo.Info("building profiles page")
+	b.profileList = listProfiles()
+	b.currentPage = buildProfiles(b.vW, b.vH, b.profileList, b.profile, infoMsg)
+	b.currentPageLocal = b.currentPage
+	go b.sendMessage(fmt.Sprintf("Profiles (current: %s)", profileName(b.profile)), thisfunc)
+	b.handle(b.buildDraw(thisfunc))
+}
+
+// profileSwitch handles both the switch links and the new profile form
+// from the profiles page
+func (b *ugglyBrowser) profileSwitch(name string) {
+	infoMsg := fmt.Sprintf("switched to profile '%s'", profileName(name))
+	if name == b.profile {
+		infoMsg = fmt.Sprintf("already using profile '%s'", profileName(name))
+	} else if err := b.switchProfile(name); err != nil {
+		loggo.Error("error switching profile", "profile", name, "error", err.Error())
+		infoMsg = fmt.Sprintf("error switching profile: %s", err.Error())
+	}
+	b.sendMessage(infoMsg, "profile-switch")
+	b.profilesPage(infoMsg)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	pb "github.com/rendicott/uggly"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sessionFileName is stored next to the cookie vault so every profile
+// restores its own session
+const sessionFileName = "session.json"
+
+// savedSession is what's needed to put the browser back where it was
+// after it exits or crashes
+type savedSession struct {
+	Ugri      string // page from a server, blank if a local page was showing
+	Stream    bool
+	LocalPage string                       // name of the local page showing, if any
+	Forms     map[string]map[string]string // unsubmitted values by form then textbox
+	Saved     string                       // RFC1123 time it was written
+	Clean     bool                         // false until the browser exits normally
+}
+
+func (b *ugglyBrowser) sessionFile() string {
+	return filepath.Join(filepath.Dir(*b.settings.VaultFile), sessionFileName)
+}
+
+func (b *ugglyBrowser) loadSession() (*savedSession, error) {
+	dat, err := ioutil.ReadFile(b.sessionFile())
+	if err != nil {
+		return nil, err
+	}
+	saved := savedSession{}
+	err = json.Unmarshal(dat, &saved)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, err
+}
+
+// currentSession captures what's on screen. Form values are only kept
+// when the restoreForms setting is on and never for password boxes.
+func (b *ugglyBrowser) currentSession() *savedSession {
+	saved := savedSession{}
+	if b.currentPageLocal != nil {
+		saved.LocalPage = b.currentPageLocal.Name
+	} else if b.sess.server != "" {
+		saved.Ugri = *b.sess.genUgri()
+		saved.Stream = b.sess.stream
+	}
+	if b.settings.RestoreForms {
+		forms := b.formState.snapshot(b.pageKey())
+		// the saver runs in the background while pages rebuild the forms
+		for name, src := range b.currentFormSources() {
+			for _, tb := range src.form.TextBoxes {
+				if tb.Password {
+					delete(forms[formKey(name)], tb.Name)
+				}
+			}
+			if len(forms[formKey(name)]) == 0 {
+				delete(forms, formKey(name))
+			}
+		}
+		if len(forms) > 0 {
+			saved.Forms = forms
+		}
+	}
+	return &saved
+}
+
+// storeSession writes the current session if it changed since the last
+// write. clean marks a normal exit so the next start doesn't think the
+// browser crashed.
+func (b *ugglyBrowser) storeSession(clean bool) (err error) {
+	b.sessionMu.Lock()
+	defer b.sessionMu.Unlock()
+	saved := b.currentSession()
+	saved.Clean = clean
+	dat, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(dat, b.savedSession) {
+		return nil
+	}
+	b.savedSession = dat
+	saved.Saved = time.Now().Format(time.RFC1123)
+	dat, err = json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	filename := b.sessionFile()
+	ensureDir(filename)
+	err = ioutil.WriteFile(filename+".tmp", dat, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// sessionSaver keeps the session file current so there is something to
+// restore if the browser crashes
+func (b *ugglyBrowser) sessionSaver() {
+	ticker := time.NewTicker(b.sessionPoll)
+	defer ticker.Stop()
+	for range ticker.C {
+		if b.exitFlag {
+			return
+		}
+		err := b.storeSession(false)
+		if err != nil {
+			loggo.Error("error storing session", "error", err.Error())
+		}
+	}
+}
+
+// sessionToRestore loads the last session and decides whether it should
+// be restored. After a crash the user is asked, after a normal exit it's
+// restored when the startup setting is restore. Nothing is restored when
+// a UGRI was given on the command line.
+func (b *ugglyBrowser) sessionToRestore(haveUgri bool) *savedSession {
+	saved, err := b.loadSession()
+	if err != nil {
+		loggo.Info("no session to restore", "error", err.Error())
+		return nil
+	}
+	if saved.Ugri == "" && saved.LocalPage == "" {
+		return nil
+	}
+	if haveUgri {
+		return nil
+	}
+	if saved.Clean {
+		if b.settings.Startup == startupRestore {
+			return saved
+		}
+		return nil
+	}
+	what := saved.Ugri
+	if what == "" {
+		what = saved.LocalPage
+	}
+	msg := "The browser did not shut down properly"
+	if saved.Saved != "" {
+		msg += " after " + saved.Saved
+	}
+	msg += ".\n\nRestore '" + what + "'"
+	if len(saved.Forms) > 0 {
+		msg += " and the form contents you hadn't submitted"
+	}
+	msg += "?\n\n(y) restore\n(n) start fresh"
+	if b.promptKey("Restore session", msg, []rune{'y', 'n'}) != 'y' {
+		return nil
+	}
+	return saved
+}
+
+// restoreSession opens the saved page putting back any form values
+// before the page's forms are built
+func (b *ugglyBrowser) restoreSession(ctx context.Context, saved *savedSession) {
+	loggo.Info("restoring session", "ugri", saved.Ugri, "localPage", saved.LocalPage)
+	if saved.LocalPage != "" {
+		b.formState.restore(saved.LocalPage, saved.Forms)
+		// refresh knows how to rebuild every local page
+		b.currentPageLocal = &pb.PageResponse{Name: saved.LocalPage}
+		b.refresh(ctx)
+		return
+	}
+	b.formState.restore(saved.Ugri, saved.Forms)
+	link, err := linkFromString(saved.Ugri)
+	if err != nil {
+		b.startPage("could not restore '" + saved.Ugri + "', it is not a valid ugri")
+		return
+	}
+	link.Stream = link.Stream || saved.Stream
+	b.sess.server = link.Server
+	b.sess.port = link.Port
+	b.sess.secure = link.Secure
+	b.sess.currPage = link.PageName
+	b.sess.stream = link.Stream
+	link, _ = b.linkFiller(link)
+	b.get2(ctx, linkRequest(link))
+}
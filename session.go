@@ -24,6 +24,7 @@ type session struct {
 	currPage        string
 	clientWidth     int32
 	clientHeight    int32
+	pins            *pinStore // the profile's certificate pins
 }
 
 func (s *session) genUgri() *string {
@@ -50,10 +51,16 @@ func (s *session) getConnection(ctx context.Context) (err error) {
 		config := &tls.Config{
 			//RootCAs: certs,
 		}
+		if s.pins != nil {
+			config.VerifyConnection = s.pins.verify(tempConnString)
+		}
 		loggo.Info("attempting secure connection", "host", tempConnString)
 		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(config)))
 		s.conn, err = grpc.DialContext(ctx, tempConnString, opts...)
 		s.secured = true
+		if err != nil && s.pins != nil && s.pins.wasRejected(tempConnString) {
+			err = fmt.Errorf("%s: %w", tempConnString, errPinMismatch)
+		}
 	} else {
 		loggo.Info("attempting insecure connection")
 		opts = append(opts, grpc.WithInsecure())
@@ -69,6 +76,18 @@ func (s *session) getConnection(ctx context.Context) (err error) {
 	return err
 }
 
+// close drops the connection to the current server if there is one
+func (s *session) close() {
+	if s.conn == nil {
+		return
+	}
+	err := s.conn.Close()
+	if err != nil {
+		loggo.Error("error closing connection", "error", err.Error())
+	}
+	s.conn = nil
+}
+
 func (s *session) prepGet(ctx context.Context, pq *pb.PageRequest) (err error) {
 	loggo.Info("current and desired connection info",
 		"rserver", pq.Server, "rport", pq.Port,
@@ -79,6 +98,7 @@ func (s *session) prepGet(ctx context.Context, pq *pb.PageRequest) (err error) {
 		loggo.Info("request for same server:port, reusing same connection")
 	} else {
 		loggo.Info("request for new server:port, establishing new connection")
+		s.close()
 		s.setServer(pq.Server, pq.Port, pq.Secure)
 		err = s.getConnection(ctx)
 	}
@@ -189,8 +209,14 @@ func (s *session) feedKeyStrokes() (keyStrokes []*pb.KeyStroke, err error) {
 		return keyStrokes, err
 	}
 	for i, page := range feed.Pages {
+		// pages past the end of the stroke map are left without a
+		// key, hint mode can still reach them
+		stroke := ""
+		if i < len(uggo.StrokeMap) {
+			stroke = uggo.StrokeMap[i]
+		}
 		keyStrokes = append(keyStrokes, &pb.KeyStroke{
-			KeyStroke: uggo.StrokeMap[i],
+			KeyStroke: stroke,
 			Action: &pb.KeyStroke_Link{
 				Link: &pb.Link{
 					PageName: page.Name,
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"gopkg.in/yaml.v3"
 	"io/ioutil"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func (b *ugglyBrowser) settingsProcess(formContents map[string]string) {
@@ -12,6 +16,8 @@ func (b *ugglyBrowser) settingsProcess(formContents map[string]string) {
 		"formContents", formContents)
 	var err error
 	changed := false
+	infoMsgs := []string{}
+	b.settingsMu.Lock()
 	for k, v := range formContents {
 		loggo.Debug("formData", "k", k, "v", v)
 		fv := v
@@ -35,6 +41,80 @@ func (b *ugglyBrowser) settingsProcess(formContents map[string]string) {
 				changed = true
 			}
 		}
+		if k == "AllowPasswordForms" {
+			servers := splitList(fv)
+			if strings.Join(servers, ",") != strings.Join(b.settings.AllowPasswordForms, ",") {
+				loggo.Debug("settings field update",
+					"k", k,
+					"b.settings.AllowPasswordForms", b.settings.AllowPasswordForms,
+					"v", fv)
+				b.settings.AllowPasswordForms = servers
+				changed = true
+			}
+		}
+		if k == "DefaultCookiePolicy" {
+			if !validCookiePolicy(fv) {
+				infoMsgs = append(infoMsgs, fmt.Sprintf(
+					"ignored invalid default cookie policy '%s'", fv))
+			} else if b.settings.DefaultCookiePolicy != fv {
+				b.settings.DefaultCookiePolicy = fv
+				changed = true
+			}
+		}
+		if k == "CookiePolicies" {
+			policies, bad := parseCookiePolicies(fv)
+			if len(bad) > 0 {
+				infoMsgs = append(infoMsgs, fmt.Sprintf(
+					"ignored invalid cookie policies, expected server:port=policy: %s",
+					strings.Join(bad, ",")))
+			}
+			if formatCookiePolicies(policies) != formatCookiePolicies(b.settings.CookiePolicies) {
+				b.settings.CookiePolicies = policies
+				changed = true
+			}
+		}
+		if k == "HomePage" {
+			fv = strings.TrimSpace(fv)
+			if _, err := linkFromString(fv); fv != "" && err != nil {
+				infoMsgs = append(infoMsgs, fmt.Sprintf(
+					"ignored invalid home page '%s'", fv))
+			} else if b.settings.HomePage != fv {
+				b.settings.HomePage = fv
+				changed = true
+			}
+		}
+		if k == "Keymap" {
+			keymap, bad := parseKeymap(fv)
+			if len(bad) > 0 {
+				infoMsgs = append(infoMsgs, fmt.Sprintf(
+					"ignored invalid keymap entries: %s", strings.Join(bad, ",")))
+			}
+			if formatKeymap(keymap) != formatKeymap(b.settings.Keymap) {
+				b.settings.Keymap = keymap
+				changed = true
+				infoMsgs = append(infoMsgs, b.settings.keymapConflicts()...)
+			}
+		}
+		if k == "RestoreForms" {
+			restore, err := strconv.ParseBool(strings.TrimSpace(fv))
+			if err != nil {
+				infoMsgs = append(infoMsgs, fmt.Sprintf(
+					"ignored invalid restore forms '%s', use true or false", fv))
+			} else if b.settings.RestoreForms != restore {
+				b.settings.RestoreForms = restore
+				changed = true
+			}
+		}
+		if k == "Startup" {
+			fv = strings.TrimSpace(fv)
+			if !validStartup(fv) {
+				infoMsgs = append(infoMsgs, fmt.Sprintf(
+					"ignored invalid startup '%s', use %s", fv, strings.Join(startupModes, ", ")))
+			} else if b.settings.Startup != fv {
+				b.settings.Startup = fv
+				changed = true
+			}
+		}
 		if strings.Contains(k, "bookmark_") {
 			// key will come in like "bookmark_ugri_1" where
 			// "1" is a string of the bookmark.uid
@@ -64,9 +144,23 @@ func (b *ugglyBrowser) settingsProcess(formContents map[string]string) {
 						bm.ShortName = &fv
 					}
 				}
+				if strings.Contains(k, "folder") && stringUidWant == currStringUid {
+					if folder := normalizeFolder(fv); bm.Folder != folder {
+						changed = true
+						bm.Folder = folder
+					}
+				}
+				if strings.Contains(k, "tags") && stringUidWant == currStringUid {
+					tags := normalizeTags(splitList(fv))
+					if strings.Join(bm.Tags, ",") != strings.Join(tags, ",") {
+						changed = true
+						bm.Tags = tags
+					}
+				}
 			}
 		}
 	}
+	b.settingsMu.Unlock()
 	infoMsg := "no settings were changed"
 	if changed {
 		infoMsg = "saved settings"
@@ -75,12 +169,51 @@ func (b *ugglyBrowser) settingsProcess(formContents map[string]string) {
 	if err != nil {
 		infoMsg = "error saving settings to disk"
 	}
+	for _, m := range infoMsgs {
+		infoMsg += ", " + m
+	}
 	b.sendMessage(infoMsg, "settings-process")
 	b.settingsPage(infoMsg)
 }
 
+// settingsVersion is the current version of the settings schema.
+// Files without a version are version 1, from before it was tracked.
+const settingsVersion = 2
+
+// settingsMigrations bring settings from one version to the next,
+// settingsMigrations[0] migrates version 1 to 2 and so on
+var settingsMigrations = []func(b *ugglyBrowser, s *ugglyBrowserSettings){
+	// version 1 defaulted the vault to a file in the CWD which has
+	// since moved to the state directory
+	func(b *ugglyBrowser, s *ugglyBrowserSettings) {
+		if s.VaultFile != nil && *s.VaultFile == legacyVaultFile {
+			vaultFile := profileVaultFile(b.profile)
+			s.VaultFile = &vaultFile
+		}
+	},
+}
+
+// settingsDefaults are used for any field missing from the file
+func (b *ugglyBrowser) settingsDefaults() *ugglyBrowserSettings {
+	defaultVaultPassEnvVar := "UGGSECP"
+	vaultFile := profileVaultFile(b.profile)
+	return &ugglyBrowserSettings{
+		Version:         settingsVersion,
+		VaultPassEnvVar: &defaultVaultPassEnvVar,
+		VaultFile:       &vaultFile,
+		Bookmarks:       make([]*BookMark, 0),
+		Startup:         startupStartPage,
+	}
+}
+
+// settingsSave writes the settings with owner only permissions. The
+// previous file is kept as a .bak and the new one is renamed into
+// place so a crash never leaves a half written config.
 func (b *ugglyBrowser) settingsSave() (err error) {
+	b.settingsMu.Lock()
+	defer b.settingsMu.Unlock()
 	filename := b.settingsFile
+	b.settings.Version = settingsVersion
 	bytes, err := yaml.Marshal(b.settings)
 	if err != nil {
 		loggo.Error("error converting settings to yaml",
@@ -88,42 +221,361 @@ func (b *ugglyBrowser) settingsSave() (err error) {
 		return err
 	}
 	loggo.Info("writing settings to disk", "filename", filename)
-	err = ioutil.WriteFile(filename, bytes, 0755)
+	if b.settingsChangedOnDisk() {
+		// somebody else wrote the file since we read it so pick
+		// up their changes instead of writing over them
+		b.settingsMerge()
+		bytes, err = yaml.Marshal(b.settings)
+		if err != nil {
+			return err
+		}
+	}
+	if old, err := ioutil.ReadFile(filename); err == nil {
+		if string(old) == string(bytes) {
+			loggo.Debug("settings unchanged, skipping write")
+			b.settingsBase = bytes
+			return nil
+		}
+		err = ioutil.WriteFile(filename+".bak", old, 0600)
+		if err != nil {
+			loggo.Error("error backing up settings file",
+				"err", err.Error(),
+				"filename", filename)
+			return err
+		}
+	}
+	tmp := filename + ".tmp"
+	err = ioutil.WriteFile(tmp, bytes, 0600)
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
 	if err != nil {
 		loggo.Error("error writing yaml to file",
 			"err", err.Error(),
 			"filename", filename)
+		return err
+	}
+	b.settingsBase = bytes
+	if fi, err := os.Stat(filename); err == nil {
+		b.settingsModTime = fi.ModTime()
 	}
 	return err
 }
 
+// settingsChangedOnDisk reports whether the settings file was written
+// by someone else since we last read or wrote it
+func (b *ugglyBrowser) settingsChangedOnDisk() bool {
+	fi, err := os.Stat(b.settingsFile)
+	return err == nil && !fi.ModTime().Equal(b.settingsModTime)
+}
+
+// settingsWatcher reloads the settings when the file is edited outside
+// the browser or by another ugglyc using the same profile
+func (b *ugglyBrowser) settingsWatcher() {
+	ticker := time.NewTicker(b.settingsPoll)
+	defer ticker.Stop()
+	for range ticker.C {
+		if b.exitFlag {
+			return
+		}
+		b.settingsMu.Lock()
+		changed := b.settingsChangedOnDisk()
+		merged := changed && b.settingsMerge()
+		b.settingsMu.Unlock()
+		if changed && !merged {
+			b.sendMessage("settings file on disk is invalid, kept the settings in memory",
+				"settings-watcher")
+		} else if changed {
+			msg := "settings reloaded from disk"
+			if len(b.settingsClashes) > 0 {
+				msg += fmt.Sprintf(", kept our own %s (changed in both places)",
+					strings.Join(b.settingsClashes, ", "))
+			}
+			b.sendMessage(msg, "settings-watcher")
+		}
+	}
+}
+
+// settingsMerge loads the settings file and merges it with the settings
+// in memory field by field. Fields changed only on disk are taken from
+// disk, fields changed only in memory are kept and fields changed in
+// both places keep the in memory value and are listed in
+// b.settingsClashes so the user can be warned. Returns false if the
+// file couldn't be used and the settings in memory were kept as is.
+func (b *ugglyBrowser) settingsMerge() bool {
+	base := settingsFields(b.settingsBase)
+	mine, _ := yaml.Marshal(b.settings)
+	ours := settingsFields(mine)
+	theirsSettings, err := b.settingsRead()
+	if err != nil {
+		// merging with the defaults settingsRead falls back to would
+		// quietly throw away every field the file was supposed to hold
+		b.settingsWarn("kept the settings in memory, the file on disk could not be used")
+		return false
+	}
+	b.settingsBase, _ = yaml.Marshal(theirsSettings)
+	theirsYaml, _ := yaml.Marshal(theirsSettings)
+	theirs := settingsFields(theirsYaml)
+	merged := make(map[string]interface{})
+	b.settingsClashes = []string{}
+	keys := make(map[string]bool)
+	for _, fields := range []map[string]settingsField{ours, theirs} {
+		for k := range fields {
+			keys[k] = true
+		}
+	}
+	for k := range keys {
+		o, t := ours[k], theirs[k]
+		switch {
+		case o.text == base[k].text || o.text == t.text:
+			merged[k] = t.value
+		case t.text == base[k].text:
+			merged[k] = o.value
+		default:
+			merged[k] = o.value
+			b.settingsClashes = append(b.settingsClashes, k)
+		}
+	}
+	sort.Strings(b.settingsClashes)
+	data, err := yaml.Marshal(merged)
+	var s *ugglyBrowserSettings
+	if err == nil {
+		s, err = b.settingsParse(data)
+	}
+	if err != nil {
+		loggo.Error("error merging settings, keeping disk version", "err", err.Error())
+		s = theirsSettings
+	}
+	// copied into place rather than swapping the pointer, everybody
+	// holds on to b.settings
+	*b.settings = *s
+	if err == nil {
+		loggo.Info("merged settings from disk",
+			"filename", b.settingsFile, "conflicts", len(b.settingsClashes))
+	}
+	return true
+}
+
+// settingsField is a top level settings field's value and its yaml
+// text which is used to compare fields across versions of the file
+type settingsField struct {
+	value interface{}
+	text  string
+}
+
+func settingsFields(data []byte) map[string]settingsField {
+	raw := make(map[string]interface{})
+	fields := make(map[string]settingsField)
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fields
+	}
+	for k, v := range raw {
+		text, _ := yaml.Marshal(v)
+		fields[k] = settingsField{value: v, text: string(text)}
+	}
+	return fields
+}
+
+// settingsLoad reads the settings file field by field so a single bad
+// field only costs that field instead of the whole file. Missing and
+// invalid fields get defaults, old versions are migrated and anything
+// that was changed on the way is recorded in b.settingsWarnings.
 func (b *ugglyBrowser) settingsLoad() *ugglyBrowserSettings {
+	s, _ := b.settingsRead()
+	// remember what's on disk so later changes to either side can be merged
+	b.settingsBase, _ = yaml.Marshal(s)
+	return s
+}
+
+// settingsRead reads the settings file. If it can't be read or parsed
+// the defaults are returned along with the error.
+func (b *ugglyBrowser) settingsRead() (*ugglyBrowserSettings, error) {
 	filename := b.settingsFile
-	s := ugglyBrowserSettings{}
 	loggo.Info("reloading settings from file", "filename", filename)
+	b.settingsWarnings = []string{}
+	if fi, err := os.Stat(filename); err == nil {
+		b.settingsModTime = fi.ModTime()
+	}
 	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		loggo.Error("error loading settings yaml file",
-			"err", err.Error(),
-			"filename", filename)
-	} else {
-		err = yaml.Unmarshal(data, &s)
+	if os.IsNotExist(err) {
+		loggo.Info("no settings file yet, using defaults", "filename", filename)
+		return b.settingsDefaults(), nil
+	} else if err != nil {
+		b.settingsWarn("could not read %s, using defaults: %s", filename, err.Error())
+		return b.settingsDefaults(), err
+	}
+	return b.settingsParse(data)
+}
+
+func (b *ugglyBrowser) settingsWarn(msg string, args ...interface{}) {
+	w := fmt.Sprintf(msg, args...)
+	loggo.Warn("settings problem", "filename", b.settingsFile, "problem", w)
+	b.settingsWarnings = append(b.settingsWarnings, w)
+}
+
+// settingsParse does the field by field decoding for settingsRead
+func (b *ugglyBrowser) settingsParse(data []byte) (*ugglyBrowserSettings, error) {
+	filename := b.settingsFile
+	s := b.settingsDefaults()
+	warn := b.settingsWarn
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err == nil && len(root.Content) > 0 && root.Content[0].Kind != yaml.MappingNode {
+		err = fmt.Errorf("top level is not a mapping")
 	}
 	if err != nil {
-		loggo.Error("error parsing yaml settings file, loading defaults instead",
-			"err", err.Error(),
-			"filename", filename)
-		defaultVaultPassEnvVar := "UGGSECP"
-		defaultVaultFile := "cookies.json.encrypted"
-		s = ugglyBrowserSettings{
-			VaultPassEnvVar: &defaultVaultPassEnvVar,
-			VaultFile:       &defaultVaultFile,
-			Bookmarks:       make([]*BookMark, 0),
+		// keep a copy so the user's bookmarks aren't lost when
+		// the defaults are saved over the file
+		backup := filename + ".invalid"
+		ioutil.WriteFile(backup, data, 0600)
+		warn("could not parse %s, a copy was saved to %s: %s",
+			filename, backup, err.Error())
+		return s, err
+	}
+	version := 1
+	if len(root.Content) > 0 {
+		fields := root.Content[0].Content
+		for i := 0; i+1 < len(fields); i += 2 {
+			key, value := fields[i].Value, fields[i+1]
+			if err := s.decodeField(key, value, &version); err != nil {
+				warn("ignoring field '%s': %s", key, err.Error())
+			}
 		}
-		err = nil
 	}
+	if version > settingsVersion {
+		warn("settings file is version %d but this browser only knows up to %d, "+
+			"unknown fields will be dropped on save", version, settingsVersion)
+	}
+	for v := version; v < settingsVersion && v-1 < len(settingsMigrations); v++ {
+		loggo.Info("migrating settings", "from", v, "to", v+1)
+		settingsMigrations[v-1](b, s)
+	}
+	for _, w := range s.validate(b.settingsDefaults()) {
+		warn(w)
+	}
+	s.Version = settingsVersion
 	s.uidifyBookmarks()
-	return &s
+	return s, nil
+}
+
+// decodeField decodes a single top level field into the settings
+func (s *ugglyBrowserSettings) decodeField(key string, value *yaml.Node, version *int) error {
+	switch key {
+	case "version":
+		return value.Decode(version)
+	case "vaultPassEnvVar":
+		return value.Decode(&s.VaultPassEnvVar)
+	case "vaultFile":
+		return value.Decode(&s.VaultFile)
+	case "bookMarks":
+		return value.Decode(&s.Bookmarks)
+	case "allowPasswordForms":
+		return value.Decode(&s.AllowPasswordForms)
+	case "cookiePolicies":
+		return value.Decode(&s.CookiePolicies)
+	case "defaultCookiePolicy":
+		return value.Decode(&s.DefaultCookiePolicy)
+	case "homePage":
+		return value.Decode(&s.HomePage)
+	case "startup":
+		return value.Decode(&s.Startup)
+	case "restoreForms":
+		return value.Decode(&s.RestoreForms)
+	case "keymap":
+		return value.Decode(&s.Keymap)
+	}
+	return fmt.Errorf("unknown field")
+}
+
+// validate fixes up fields that decoded but make no sense, merging in
+// defaults where needed, and returns a description of each fix
+func (s *ugglyBrowserSettings) validate(defaults *ugglyBrowserSettings) []string {
+	problems := []string{}
+	if s.VaultPassEnvVar == nil || *s.VaultPassEnvVar == "" {
+		s.VaultPassEnvVar = defaults.VaultPassEnvVar
+	}
+	if s.VaultFile == nil || *s.VaultFile == "" {
+		s.VaultFile = defaults.VaultFile
+	}
+	bookmarks := make([]*BookMark, 0, len(s.Bookmarks))
+	for i, bm := range s.Bookmarks {
+		if bm == nil || bm.Ugri == nil || strings.TrimSpace(*bm.Ugri) == "" {
+			problems = append(problems, fmt.Sprintf("dropped bookmark %d without a ugri", i+1))
+			continue
+		}
+		if _, err := linkFromString(*bm.Ugri); err != nil {
+			problems = append(problems, fmt.Sprintf("bookmark '%s' is not a valid ugri", *bm.Ugri))
+		}
+		if bm.ShortName == nil || *bm.ShortName == "" {
+			shortName := *bm.Ugri
+			bm.ShortName = &shortName
+		}
+		bm.Folder = normalizeFolder(bm.Folder)
+		bm.Tags = normalizeTags(bm.Tags)
+		if bm.LastVisited != "" {
+			if _, err := time.Parse(time.RFC1123, bm.LastVisited); err != nil {
+				problems = append(problems, fmt.Sprintf(
+					"cleared invalid lastVisited for bookmark '%s'", *bm.ShortName))
+				bm.LastVisited = ""
+			}
+		}
+		bookmarks = append(bookmarks, bm)
+	}
+	s.Bookmarks = bookmarks
+	servers := []string{}
+	for _, server := range s.AllowPasswordForms {
+		if !strings.Contains(server, ":") {
+			problems = append(problems, fmt.Sprintf(
+				"dropped allowPasswordForms entry '%s', expected server:port", server))
+			continue
+		}
+		servers = append(servers, server)
+	}
+	s.AllowPasswordForms = servers
+	for server, policy := range s.CookiePolicies {
+		if !strings.Contains(server, ":") {
+			problems = append(problems, fmt.Sprintf(
+				"dropped cookie policy for '%s', expected server:port", server))
+			delete(s.CookiePolicies, server)
+			continue
+		}
+		if !validCookiePolicy(policy) || policy == cookiePolicyPrompt {
+			problems = append(problems, fmt.Sprintf(
+				"dropped invalid cookie policy '%s' for '%s'", policy, server))
+			delete(s.CookiePolicies, server)
+		}
+	}
+	if s.DefaultCookiePolicy != "" && !validCookiePolicy(s.DefaultCookiePolicy) {
+		problems = append(problems, fmt.Sprintf(
+			"invalid defaultCookiePolicy '%s', using allow", s.DefaultCookiePolicy))
+		s.DefaultCookiePolicy = ""
+	}
+	if s.HomePage != "" {
+		if _, err := linkFromString(s.HomePage); err != nil {
+			problems = append(problems, fmt.Sprintf(
+				"dropped homePage '%s', it is not a valid ugri", s.HomePage))
+			s.HomePage = ""
+		}
+	}
+	for action, key := range s.Keymap {
+		spec, err := parseKey(key)
+		if !validAction(action) || err != nil {
+			problems = append(problems, fmt.Sprintf(
+				"dropped keymap entry '%s: %s'", action, key))
+			delete(s.Keymap, action)
+			continue
+		}
+		s.Keymap[action] = spec.String()
+	}
+	problems = append(problems, s.keymapConflicts()...)
+	if s.Startup == "" {
+		s.Startup = defaults.Startup
+	} else if !validStartup(s.Startup) {
+		problems = append(problems, fmt.Sprintf(
+			"invalid startup '%s', using %s", s.Startup, defaults.Startup))
+		s.Startup = defaults.Startup
+	}
+	return problems
 }
 
 func (s *ugglyBrowserSettings) uidifyBookmarks() {
@@ -159,9 +611,9 @@ func (s *ugglyBrowserSettings) deleteBookmark(uid int) bool {
 	return found
 }
 
-func (s *ugglyBrowserSettings) addBookmark(shortName, ugri string) {
+func (s *ugglyBrowserSettings) addBookmark(shortName, ugri string) *BookMark {
 	if shortName == "" {
-		shortName = "added"
+		shortName = ugri
 	}
 	b := &BookMark{
 		ShortName: &shortName,
@@ -169,17 +621,164 @@ func (s *ugglyBrowserSettings) addBookmark(shortName, ugri string) {
 	}
 	s.Bookmarks = append(s.Bookmarks, b)
 	s.uidifyBookmarks()
+	return b
+}
+
+// findBookmark returns the bookmark for the UGRI if there is one
+func (s *ugglyBrowserSettings) findBookmark(ugri string) *BookMark {
+	ugri = normalizeUgri(ugri)
+	for _, bm := range s.Bookmarks {
+		if normalizeUgri(*bm.Ugri) == ugri {
+			return bm
+		}
+	}
+	return nil
+}
+
+// splitList turns a comma separated form value into a list
+// dropping any blank entries
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// passwordFormsAllowed returns true if the user said to always allow
+// password forms to be sent insecurely to the server:port
+func (s *ugglyBrowserSettings) passwordFormsAllowed(server string) bool {
+	for _, allowed := range s.AllowPasswordForms {
+		if allowed == server {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ugglyBrowserSettings) allowPasswordForms(server string) {
+	if !s.passwordFormsAllowed(server) {
+		s.AllowPasswordForms = append(s.AllowPasswordForms, server)
+	}
+}
+
+// cookie policies decide what happens to cookies a server sets
+const (
+	cookiePolicyAllow   = "allow"   // keep, persistent cookies are stored on exit
+	cookiePolicySession = "session" // keep, but drop everything on exit
+	cookiePolicyBlock   = "block"   // never accept cookies
+	cookiePolicyPrompt  = "prompt"  // only as default, ask the first time
+)
+
+func validCookiePolicy(policy string) bool {
+	switch policy {
+	case cookiePolicyAllow, cookiePolicySession, cookiePolicyBlock, cookiePolicyPrompt:
+		return true
+	}
+	return false
+}
+
+// cookiePolicy returns the policy for a server:port like
+// AllowPasswordForms falling back to the default policy and finally to
+// allow which was the original behavior
+func (s *ugglyBrowserSettings) cookiePolicy(site string) string {
+	if p, ok := s.CookiePolicies[site]; ok && validCookiePolicy(p) && p != cookiePolicyPrompt {
+		return p
+	}
+	if validCookiePolicy(s.DefaultCookiePolicy) {
+		return s.DefaultCookiePolicy
+	}
+	return cookiePolicyAllow
+}
+
+// serverCookiePolicy returns the strictest policy of any port on a
+// server. Cookies are stored per server without the port like HTTP so a
+// session or block policy on one port keeps them off disk for all.
+func (s *ugglyBrowserSettings) serverCookiePolicy(server string) string {
+	policy := s.cookiePolicy("")
+	strictness := map[string]int{
+		cookiePolicyAllow: 0, cookiePolicyPrompt: 0,
+		cookiePolicySession: 1, cookiePolicyBlock: 2,
+	}
+	for site, p := range s.CookiePolicies {
+		if strings.HasPrefix(site, server+":") && validCookiePolicy(p) &&
+			strictness[p] > strictness[policy] {
+			policy = p
+		}
+	}
+	return policy
+}
+
+func (s *ugglyBrowserSettings) setCookiePolicy(site, policy string) {
+	if s.CookiePolicies == nil {
+		s.CookiePolicies = make(map[string]string)
+	}
+	s.CookiePolicies[site] = policy
+}
+
+// parseCookiePolicies reads the settings form's "server:port=policy,..."
+// format returning the valid policies and any entries it couldn't use
+func parseCookiePolicies(value string) (map[string]string, []string) {
+	policies := make(map[string]string)
+	bad := []string{}
+	for _, entry := range splitList(value) {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || !strings.Contains(kv[0], ":") ||
+			!validCookiePolicy(strings.TrimSpace(kv[1])) ||
+			strings.TrimSpace(kv[1]) == cookiePolicyPrompt {
+			bad = append(bad, entry)
+			continue
+		}
+		policies[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return policies, bad
+}
+
+// formatCookiePolicies is the inverse of parseCookiePolicies
+func formatCookiePolicies(policies map[string]string) string {
+	entries := []string{}
+	for server, policy := range policies {
+		entries = append(entries, fmt.Sprintf("%s=%s", server, policy))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
 }
 
 type ugglyBrowserSettings struct {
+	// schema version, see settingsMigrations
+	Version int `yaml:"version"`
 	// the ENV var that stores the vault encryption password
 	VaultPassEnvVar *string     `yaml:"vaultPassEnvVar"`
 	VaultFile       *string     `yaml:"vaultFile"`
 	Bookmarks       []*BookMark `yaml:"bookMarks"`
+	// server:port list where password forms may be sent without
+	// asking even though the connection is insecure or cross site
+	AllowPasswordForms []string `yaml:"allowPasswordForms"`
+	// per server:port cookie policy (allow, session, block) and the
+	// policy for servers not listed which can also be prompt
+	CookiePolicies      map[string]string `yaml:"cookiePolicies"`
+	DefaultCookiePolicy string            `yaml:"defaultCookiePolicy"`
+	// UGRI opened by the Home key and what to show when the browser
+	// starts without a UGRI, see validStartup
+	HomePage string `yaml:"homePage"`
+	Startup  string `yaml:"startup"`
+	// keep unsubmitted form values, except passwords, in the session
+	// file so they can be restored after a crash
+	RestoreForms bool `yaml:"restoreForms"`
+	// action to key overrides for the menu, e.g., settings: Alt-s,
+	// see menuActions for the actions and their default keys
+	Keymap map[string]string `yaml:"keymap"`
 }
 
 type BookMark struct {
-	Ugri      *string `yaml:"ugri"`
-	ShortName *string `yaml:"shortName"`
-	uid       *int
+	Ugri        *string  `yaml:"ugri" json:"ugri"`
+	ShortName   *string  `yaml:"shortName" json:"shortName"`
+	Folder      string   `yaml:"folder,omitempty" json:"folder,omitempty"` // e.g., "work/internal"
+	Tags        []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	LastVisited string   `yaml:"lastVisited,omitempty" json:"lastVisited,omitempty"` // RFC1123
+	uid         *int
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// settingsBrowser returns a browser whose settings were loaded from a
+// file holding base
+func settingsBrowser(t *testing.T, base func(s *ugglyBrowserSettings)) *ugglyBrowser {
+	b := &ugglyBrowser{settingsFile: filepath.Join(t.TempDir(), "config.yml")}
+	s := b.settingsDefaults()
+	if base != nil {
+		base(s)
+	}
+	writeSettings(t, b, s)
+	b.settings = b.settingsLoad()
+	return b
+}
+
+func writeSettings(t *testing.T, b *ugglyBrowser, s *ugglyBrowserSettings) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b.settingsFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSettingsMerge(t *testing.T) {
+	home := func(h string) func(s *ugglyBrowserSettings) {
+		return func(s *ugglyBrowserSettings) { s.HomePage = h }
+	}
+	tests := []struct {
+		name        string
+		ours        func(s *ugglyBrowserSettings)
+		theirs      func(s *ugglyBrowserSettings)
+		wantHome    string
+		wantRestore bool
+		wantClashes []string
+	}{
+		{"nothing changed", nil, nil, "ugtp://base:8888/page", false, []string{}},
+		{"changed on disk", nil, home("ugtp://disk:8888/page"),
+			"ugtp://disk:8888/page", false, []string{}},
+		{"changed in memory", home("ugtp://mem:8888/page"), nil,
+			"ugtp://mem:8888/page", false, []string{}},
+		{"same change in both", home("ugtp://same:8888/page"), home("ugtp://same:8888/page"),
+			"ugtp://same:8888/page", false, []string{}},
+		{"different fields", home("ugtp://mem:8888/page"),
+			func(s *ugglyBrowserSettings) { s.RestoreForms = true },
+			"ugtp://mem:8888/page", true, []string{}},
+		{"same field in both", home("ugtp://mem:8888/page"), home("ugtp://disk:8888/page"),
+			"ugtp://mem:8888/page", false, []string{"homePage"}},
+	}
+	for _, tt := range tests {
+		b := settingsBrowser(t, home("ugtp://base:8888/page"))
+		theirs := b.settingsDefaults()
+		theirs.HomePage = "ugtp://base:8888/page"
+		if tt.theirs != nil {
+			tt.theirs(theirs)
+		}
+		writeSettings(t, b, theirs)
+		if tt.ours != nil {
+			tt.ours(b.settings)
+		}
+		held := b.settings
+		if !b.settingsMerge() {
+			t.Errorf("%s: merge failed", tt.name)
+			continue
+		}
+		if b.settings != held {
+			t.Errorf("%s: merge replaced the settings others hold on to", tt.name)
+		}
+		if b.settings.HomePage != tt.wantHome {
+			t.Errorf("%s: homePage %q, want %q", tt.name, b.settings.HomePage, tt.wantHome)
+		}
+		if b.settings.RestoreForms != tt.wantRestore {
+			t.Errorf("%s: restoreForms %t, want %t", tt.name, b.settings.RestoreForms, tt.wantRestore)
+		}
+		if !reflect.DeepEqual(b.settingsClashes, tt.wantClashes) {
+			t.Errorf("%s: clashes %v, want %v", tt.name, b.settingsClashes, tt.wantClashes)
+		}
+	}
+}
+
+func TestSettingsMergeInvalidFile(t *testing.T) {
+	b := settingsBrowser(t, func(s *ugglyBrowserSettings) {
+		s.addBookmark("work", "ugtp://work:8888/home")
+		s.Keymap = map[string]string{"exit": "Ctrl-Q"}
+	})
+	err := ioutil.WriteFile(b.settingsFile, []byte("bookMarks: [\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.settingsMerge() {
+		t.Errorf("merge with an unparsable file should fail")
+	}
+	if len(b.settings.Bookmarks) != 1 || *b.settings.Bookmarks[0].ShortName != "work" {
+		t.Errorf("bookmarks lost, got %d", len(b.settings.Bookmarks))
+	}
+	if b.settings.Keymap["exit"] != "Ctrl-Q" {
+		t.Errorf("keymap lost, got %v", b.settings.Keymap)
+	}
+	if err := b.settingsSave(); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := b.settingsRead()
+	if len(saved.Bookmarks) != 1 || saved.Keymap["exit"] != "Ctrl-Q" {
+		t.Errorf("save wrote %d bookmarks and keymap %v over the file",
+			len(saved.Bookmarks), saved.Keymap)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// what the browser shows when it's started without a UGRI
+const (
+	startupBlank     = "blank"     // an empty screen, as before there was a setting
+	startupHome      = "home"      // the home page
+	startupRestore   = "restore"   // the last page visited
+	startupBookmarks = "bookmarks" // the bookmarks page
+	startupStartPage = "startpage" // a local page with recent history and bookmarks
+)
+
+var startupModes = []string{
+	startupBlank, startupHome, startupRestore, startupBookmarks, startupStartPage}
+
+func validStartup(mode string) bool {
+	return containsString(startupModes, mode)
+}
+
+// openUgri points the session at the UGRI and gets the page. It's used
+// as a bootstrap since no server can send us any links yet.
+func (b *ugglyBrowser) openUgri(ctx context.Context, ugri string) error {
+	startLink, err := linkFromString(ugri)
+	if err != nil {
+		return err
+	}
+	b.sess.server = startLink.Server
+	b.sess.port = startLink.Port
+	b.sess.secure = startLink.Secure
+	b.sess.currPage = startLink.PageName
+	b.sess.stream = startLink.Stream
+	loggo.Info("getting page from server", "ugri", ugri)
+	startLink, _ = b.linkFiller(startLink)
+	b.get2(ctx, linkRequest(startLink))
+	return nil
+}
+
+// startup decides what to show when no UGRI was given on the command
+// line according to the startup setting
+func (b *ugglyBrowser) startup(ctx context.Context) {
+	mode := b.settings.Startup
+	loggo.Info("no start link, using startup setting", "startup", mode)
+	switch mode {
+	case startupHome:
+		b.goHome(ctx)
+	case startupRestore:
+		if len(b.history) == 0 {
+			b.startPage("nothing to restore, no pages have been visited yet")
+			return
+		}
+		if err := b.openUgri(ctx, b.history[0].Ugri); err != nil {
+			b.startPage(fmt.Sprintf("could not restore '%s'", b.history[0].Ugri))
+		}
+	case startupBookmarks:
+		b.bookmarksPage()
+	case startupBlank:
+		go b.sendMessage(fmt.Sprintf("enter an address with %s",
+			b.settings.actionKey(actionAddress)), "start-blank")
+	default:
+		b.startPage("")
+	}
+}
+
+// goHome opens the home page or the start page if there isn't one
+func (b *ugglyBrowser) goHome(ctx context.Context) {
+	if b.settings.HomePage == "" {
+		b.startPage(fmt.Sprintf("no home page set, pick one on the Settings page (%s)",
+			b.settings.actionKey(actionSettings)))
+		return
+	}
+	if err := b.openUgri(ctx, b.settings.HomePage); err != nil {
+		b.startPage(fmt.Sprintf("home page '%s' is not a valid ugri", b.settings.HomePage))
+	}
+}
+
+// recentBookmarks returns the bookmarks most recently visited first
+// followed by ones that were never visited in their usual order
+func (s *ugglyBrowserSettings) recentBookmarks() []*BookMark {
+	recent := append([]*BookMark{}, s.Bookmarks...)
+	visited := func(bm *BookMark) time.Time {
+		t, _ := time.Parse(time.RFC1123, bm.LastVisited)
+		return t
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return visited(recent[i]).After(visited(recent[j]))
+	})
+	return recent
+}
+
+func (b *ugglyBrowser) startPage(infoMsg string) {
+	thisfunc := "startPage"
+	loggo.Info("building start page")
+	b.currentPage = buildStartPage(b.vW, b.vH, b.history,
+		b.settings.recentBookmarks(), b.settings.HomePage, infoMsg, b.settings.actionKey)
+	b.currentPageLocal = b.currentPage
+	go b.sendMessage(fmt.Sprintf("Start Page - enter an address with %s",
+		b.settings.actionKey(actionAddress)), thisfunc)
+	b.handle(b.buildDraw(thisfunc))
+}
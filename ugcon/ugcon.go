@@ -7,7 +7,9 @@ import (
 	"github.com/inconshreveable/log15"
 	"github.com/rendicott/uggly"
 	"github.com/rendicott/uggly-client/boxes"
+	"github.com/rendicott/uggly-client/widgets"
 	"github.com/rendicott/ugform"
+	"strings"
 )
 
 var Loggo log15.Logger
@@ -89,11 +91,12 @@ func ConvertFormLocalForm (uf *uggly.Form, s tcell.Screen) (*ugform.Form, error)
 	u.Name = uf.Name
 	u.SubmitAction = uf.SubmitLink
 	for _, tb := range uf.TextBoxes {
+		description, _ := ParseFieldHints(tb.Description)
 		u.AddTextBox(&ugform.AddTextBoxInput{
 			Name: tb.Name,
 			TabOrder: int(tb.TabOrder),
 			DefaultValue: tb.DefaultValue,
-			Description: tb.Description,
+			Description: description,
 			PositionX: int(tb.PositionX),
 			PositionY: int(tb.PositionY),
 			Height: int(tb.Height),
@@ -108,3 +111,150 @@ func ConvertFormLocalForm (uf *uggly.Form, s tcell.Screen) (*ugform.Form, error)
 	}
 	return u, err
 }
+
+// hintOpen and hintClose delimit the field hints a server can tack
+// onto the end of a TextBox Description, e.g.,
+//   "Color {{widget=select;options=red,green,blue}}"
+// Hints are ';' separated, "\;" is a ';' inside a value, e.g., in a
+// pattern, and a hint without '=' is a flag.
+const (
+	hintOpen  = "{{"
+	hintClose = "}}"
+)
+
+// Widget kinds a server can ask for with the "widget" field hint
+const (
+	WidgetText     = "text"
+	WidgetCheckbox = "checkbox"
+	WidgetRadio    = "radio"
+	WidgetSelect   = "select"
+	WidgetTextArea = "textarea"
+)
+
+// ParseFieldHints splits a TextBox Description into the text that
+// should be displayed and the field hints that followed it
+func ParseFieldHints(desc string) (string, map[string]string) {
+	hints := make(map[string]string)
+	start := strings.LastIndex(desc, hintOpen)
+	if start < 0 || !strings.HasSuffix(strings.TrimSpace(desc), hintClose) {
+		return desc, hints
+	}
+	block := strings.TrimSpace(desc[start+len(hintOpen):])
+	block = strings.TrimSuffix(block, hintClose)
+	for _, hint := range splitHints(block) {
+		hint = strings.TrimSpace(hint)
+		if hint == "" {
+			continue
+		}
+		kv := strings.SplitN(hint, "=", 2)
+		if strings.TrimSpace(kv[0]) == "" {
+			// "=value" names nothing, drop it
+			continue
+		}
+		if len(kv) == 2 {
+			hints[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		} else {
+			hints[kv[0]] = ""
+		}
+	}
+	return strings.TrimSpace(desc[:start]), hints
+}
+
+// splitHints splits a hint block on the ';' that aren't escaped as
+// "\;". Other backslashes are kept so patterns like \d still work.
+func splitHints(block string) []string {
+	hints := []string{}
+	var hint strings.Builder
+	for i := 0; i < len(block); i++ {
+		switch {
+		case block[i] == '\\' && i+1 < len(block) && block[i+1] == ';':
+			hint.WriteByte(';')
+			i++
+		case block[i] == ';':
+			hints = append(hints, hint.String())
+			hint.Reset()
+		default:
+			hint.WriteByte(block[i])
+		}
+	}
+	return append(hints, hint.String())
+}
+
+// HasWidgets returns true if any of the form's TextBoxes asks to be
+// drawn as something other than a plain textbox
+func HasWidgets(uf *uggly.Form) bool {
+	for _, tb := range uf.TextBoxes {
+		_, hints := ParseFieldHints(tb.Description)
+		if kind, ok := hints["widget"]; ok && kind != WidgetText {
+			return true
+		}
+	}
+	return false
+}
+
+// convertStyle is setStyle for a possibly nil uggly Style
+func convertStyle(st *uggly.Style) tcell.Style {
+	if st == nil {
+		return tcell.StyleDefault
+	}
+	return *setStyle(st.Fg, st.Bg)
+}
+
+// ConvertFormLocalWidgetForm converts an uggly Form whose TextBoxes carry
+// widget hints into a widgets.Form. Widgets the client doesn't know
+// about, or choice widgets sent without options, fall back to
+// plain textboxes so the form is still usable.
+func ConvertFormLocalWidgetForm(uf *uggly.Form, s tcell.Screen) (*widgets.Form, error) {
+	var err error
+	w := widgets.NewForm(s)
+	w.Name = uf.Name
+	w.SubmitAction = uf.SubmitLink
+	for _, tb := range uf.TextBoxes {
+		description, hints := ParseFieldHints(tb.Description)
+		in := &widgets.Input{
+			Name:            tb.Name,
+			TabOrder:        int(tb.TabOrder),
+			Description:     description,
+			ShowDescription: tb.ShowDescription,
+			PositionX:       int(tb.PositionX),
+			PositionY:       int(tb.PositionY),
+			Width:           int(tb.Width),
+			Height:          int(tb.Height),
+			Styles: widgets.Styles{
+				Text:        convertStyle(tb.StyleText),
+				Fill:        convertStyle(tb.StyleFill),
+				Cursor:      convertStyle(tb.StyleCursor),
+				Description: convertStyle(tb.StyleDescription),
+			},
+		}
+		var options []string
+		for _, opt := range strings.Split(hints["options"], ",") {
+			if opt = strings.TrimSpace(opt); opt != "" {
+				options = append(options, opt)
+			}
+		}
+		kind := hints["widget"]
+		switch kind {
+		case WidgetCheckbox:
+			checked := tb.DefaultValue == "true" || tb.DefaultValue == "x"
+			w.Add(widgets.NewCheckbox(in, checked))
+		case WidgetRadio, WidgetSelect:
+			if len(options) == 0 {
+				Loggo.Info("choice widget sent without options, falling back to text",
+					"widget", kind, "name", tb.Name)
+				w.Add(widgets.NewTextArea(in, tb.DefaultValue, tb.Password))
+			} else if kind == WidgetRadio {
+				w.Add(widgets.NewRadioGroup(in, options, tb.DefaultValue))
+			} else {
+				w.Add(widgets.NewSelect(in, options, tb.DefaultValue))
+			}
+		case WidgetTextArea, WidgetText, "":
+			w.Add(widgets.NewTextArea(in, tb.DefaultValue, tb.Password))
+		default:
+			Loggo.Info("unknown widget, falling back to text",
+				"widget", kind, "name", tb.Name)
+			w.Add(widgets.NewTextArea(in, tb.DefaultValue, tb.Password))
+		}
+	}
+	return w, err
+}
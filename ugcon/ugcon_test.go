@@ -0,0 +1,81 @@
+package ugcon
+
+import (
+	"github.com/rendicott/uggly"
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldHints(t *testing.T) {
+	tests := []struct {
+		name      string
+		desc      string
+		wantDesc  string
+		wantHints map[string]string
+	}{
+		{"no hints", "Color", "Color", map[string]string{}},
+		{"empty", "", "", map[string]string{}},
+		{"widget and options", "Color {{widget=select;options=red,green,blue}}", "Color",
+			map[string]string{"widget": "select", "options": "red,green,blue"}},
+		{"flag without value", "Terms {{widget=checkbox;required}}", "Terms",
+			map[string]string{"widget": "checkbox", "required": ""}},
+		{"spaces around hints", "Size {{ widget = radio ; options = s,m,l }}  ", "Size",
+			map[string]string{"widget": "radio", "options": "s,m,l"}},
+		{"only hints", "{{widget=textarea}}", "",
+			map[string]string{"widget": "textarea"}},
+		{"empty block", "Name {{}}", "Name", map[string]string{}},
+		{"empty hints between separators", "Name {{;;widget=text;}}", "Name",
+			map[string]string{"widget": "text"}},
+		{"value containing '='", "Expr {{pattern=a=b}}", "Expr",
+			map[string]string{"pattern": "a=b"}},
+		{"empty options", "Color {{widget=select;options=}}", "Color",
+			map[string]string{"widget": "select", "options": ""}},
+		{"unclosed block", "Color {{widget=select", "Color {{widget=select",
+			map[string]string{}},
+		{"text after block", "Color {{widget=select}} please", "Color {{widget=select}} please",
+			map[string]string{}},
+		{"close without open", "Color widget=select}}", "Color widget=select}}",
+			map[string]string{}},
+		{"missing key", "Color {{=select;widget=radio}}", "Color",
+			map[string]string{"widget": "radio"}},
+		{"escaped separator", `Code {{pattern=a\;b;required}}`, "Code",
+			map[string]string{"pattern": "a;b", "required": ""}},
+		{"other backslashes kept", `Zip {{pattern=\d{5}\;?}}`, "Zip",
+			map[string]string{"pattern": `\d{5};?`}},
+		{"trailing backslash", `Path {{x=a\}}`, "Path",
+			map[string]string{"x": `a\`}},
+		{"last block wins", "a {{x=1}} b {{widget=select}}", "a {{x=1}} b",
+			map[string]string{"widget": "select"}},
+	}
+	for _, tt := range tests {
+		desc, hints := ParseFieldHints(tt.desc)
+		if desc != tt.wantDesc {
+			t.Errorf("%s: description %q, want %q", tt.name, desc, tt.wantDesc)
+		}
+		if !reflect.DeepEqual(hints, tt.wantHints) {
+			t.Errorf("%s: hints %v, want %v", tt.name, hints, tt.wantHints)
+		}
+	}
+}
+
+func TestHasWidgets(t *testing.T) {
+	tests := []struct {
+		name  string
+		descs []string
+		want  bool
+	}{
+		{"plain textboxes", []string{"Name", "Email"}, false},
+		{"text widget", []string{"Name {{widget=text}}"}, false},
+		{"select", []string{"Name", "Color {{widget=select;options=}}"}, true},
+		{"malformed hint", []string{"Color {{widget=select"}, false},
+	}
+	for _, tt := range tests {
+		form := &uggly.Form{}
+		for _, d := range tt.descs {
+			form.TextBoxes = append(form.TextBoxes, &uggly.TextBox{Description: d})
+		}
+		if got := HasWidgets(form); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
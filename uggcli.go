@@ -11,12 +11,17 @@ import (
 	"github.com/rendicott/uggo"
 	pb "github.com/rendicott/uggly"
 	"github.com/rendicott/uggly-client/boxes"
+	"github.com/rendicott/uggly-client/cookiejar"
 	"github.com/rendicott/uggly-client/ugcon"
+	"github.com/rendicott/uggly-client/widgets"
 	"github.com/rendicott/uggsec"
 	"net/url"
 	"os"
+	"os/signal"
 	"strings"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -24,7 +29,8 @@ import (
 var version string
 
 var (
-	logFile  = "uggcli.log.json"
+	logFile  = flag.String("log-file", "", "filename for the JSON log. Defaults to "+
+		"uggcli.log.json under $XDG_CACHE_HOME/ugglyc (~/.cache/ugglyc)")
 	logLevel = flag.String("loglevel", "info", "log level 'info' or 'debug'")
 	breaks = flag.Bool("breaks", false, "when set the program will stop at various" +
 		" points so the log can be read easier")
@@ -35,16 +41,43 @@ var (
 		"variable instead. This flag causes the browser to generate an uggsec "+
 		"vault encryption password and dump to STDOUT. Useful when used in "+
 		"conjunction with commands like `export UGGSECP=$(ugglyc -vault-pass-gen)`")
+	vaultRotate = flag.Bool("vault-rotate", false, "re-encrypts the cookie vault "+
+		"and saved logins with a newly generated password and exits. The new "+
+		"password replaces the old one in the OS keyring or is printed to "+
+		"STDOUT for systems using the `vault-password-env-var` instead.")
 	vaultEnvVar = flag.String("vault-password-env-var", "UGGSECP", "The ENV var that "+
 		"is used to store the vault encryption password on systems that do no support "+
 		"an OS keyring. See `vault-pass-gen` flag for generating password")
-	vaultFile = flag.String("vault-file", "cookies.json.encrypted", "filename where "+
-		"encrypted cookies are stored. Encryption key will try to be stored in OS "+
+	cookiesExport = flag.String("cookies-export", "", "export the persistent "+
+		"cookies from the vault to this unencrypted JSON file and exit. "+
+		"See `cookies-servers` to limit the export to some servers.")
+	cookiesImport = flag.String("cookies-import", "", "import cookies from a "+
+		"file written by `cookies-export` into the vault and exit. Cookies "+
+		"for each imported server replace the ones already in the vault.")
+	cookiesServers = flag.String("cookies-servers", "", "comma separated list of "+
+		"servers to limit `cookies-export` and `cookies-import` to")
+	bookmarksExport = flag.String("bookmarks-export", "", "export bookmarks to "+
+		"this file and exit. The format is picked from the extension: .yml, "+
+		".json or .html for a Netscape bookmark file. See `bookmarks-folder` to "+
+		"export a single folder.")
+	bookmarksImport = flag.String("bookmarks-import", "", "import bookmarks from "+
+		"a .yml, .json or .html file and exit. Bookmarks whose UGRI is already "+
+		"bookmarked are skipped.")
+	bookmarksFolder = flag.String("bookmarks-folder", "", "folder, e.g., "+
+		"work/internal, that `bookmarks-export` exports and `bookmarks-import` "+
+		"imports into")
+	vaultFile = flag.String("vault-file", "", "filename where "+
+		"encrypted cookies are stored, by default cookies.json.encrypted under "+
+		"$XDG_STATE_HOME/ugglyc (~/.local/state/ugglyc). Encryption key will try to be stored in OS "+
 		"keyring if available otherwise you'll have to manually generate a password "+
 		"and set an ENV var. See `vault-password-env-var` and `vault-pass-gen` "+
 		"for more details.")
-	configFile = flag.String("config", "config.yml", "filename where browser settings " +
-		"are stored. Command parameters will always override settings loaded from file.")
+	profile = flag.String("profile", "", "name of the browser profile to use. Each "+
+		"profile has its own settings, bookmarks, cookies and saved logins. "+
+		"Profiles can also be switched and created from the Profiles page (F11).")
+	configFile = flag.String("config", "", "filename where browser settings " +
+		"are stored, by default config.yml under $XDG_CONFIG_HOME/ugglyc "+
+		"(~/.config/ugglyc). Command parameters will always override settings loaded from file.")
 )
 
 // loggo is the global logger
@@ -195,6 +228,10 @@ func (b *ugglyBrowser) processPageForms(page *pb.PageResponse, isMenu bool, labe
 		"label", label, "startingForms", len(b.forms),
 		"tags", debugTags)
 	b.forms = make([]*ugform.Form, 0) // purge existing forms
+	b.widgetForms = make([]*widgets.Form, 0)
+	// built on the side and swapped in whole since form goroutines
+	// may be looking up the old ones meanwhile
+	sources := make(map[string]*formSource)
 	if isMenu {
 		loggo.Debug("job flagged as isMenu so purging menu forms",
 			"tags", debugTags)
@@ -204,7 +241,26 @@ func (b *ugglyBrowser) processPageForms(page *pb.PageResponse, isMenu bool, labe
 		for _, form := range page.Elements.Forms {
 			loggo.Debug("convering page form to ugform",
 				"tags", debugTags)
-			f, err := ugcon.ConvertFormLocalForm(form, b.view)
+			var src *formSource
+			if !isMenu {
+				// put back anything the user typed before the page
+				// was rebuilt, e.g., by a resize or refresh
+				src = b.newFormSource(form)
+				form = b.formState.apply(src.pageKey, form)
+			}
+			var pf pageForm
+			var f *ugform.Form
+			var wf *widgets.Form
+			var err error
+			if !isMenu && ugcon.HasWidgets(form) {
+				// ugform only knows textboxes so anything
+				// fancier is polled by our own widgets
+				wf, err = ugcon.ConvertFormLocalWidgetForm(form, b.view)
+				pf = wf
+			} else {
+				f, err = ugcon.ConvertFormLocalForm(form, b.view)
+				pf = f
+			}
 			if err != nil {
 				loggo.Error("error processing form", "err", err.Error(), "label", label)
 				continue
@@ -228,19 +284,30 @@ func (b *ugglyBrowser) processPageForms(page *pb.PageResponse, isMenu bool, labe
 					if !isMenu {
 						sY += b.menuHeight
 					}
-					f.ShiftXY(sX, sY)
+					pf.ShiftXY(sX, sY)
+					if src != nil {
+						src.shiftX, src.shiftY = sX, sY
+					}
 				}
 			}
-			if isMenu {
+			if wf != nil {
+				wf.Repaint = func() { b.drawContent("widget-repaint") }
+				b.widgetForms = append(b.widgetForms, wf)
+				sources[wf.Name] = src
+			} else if isMenu {
 				b.menuForms = append(b.menuForms, f)
 			} else {
 				loggo.Debug("adding page form to b.forms",
 					"beforeAdd", len(b.forms),
 					"tags", debugTags)
 				b.forms = append(b.forms, f)
+				sources[f.Name] = src
 			}
 		}
 	}
+	b.formSourcesMu.Lock()
+	b.formSources = sources
+	b.formSourcesMu.Unlock()
 	// always add back the menu forms
 	loggo.Debug("before adding back menu forms",
 		"beforeAddMenuForms", len(b.forms), "numMenuForms", len(b.menuForms),
@@ -266,7 +333,8 @@ func (b *ugglyBrowser) buildContentMenu(label string) {
 		msg = ""
 	}
 	localPage := buildPageMenu(
-		b.vW, b.menuHeight, b.sess.server, b.sess.port, b.sess.currPage, msg, b.sess.secure)
+		b.vW, b.menuHeight, b.sess.server, b.sess.port, b.sess.currPage, msg, b.sess.secure,
+		b.settings.keyBindings())
 	b.parseKeyStrokes(localPage, true) // retain keyStrokes when injecting Menu
 	loggo.Debug("after menu build have forms",
 		"pageForms", len(localPage.Elements.Forms),
@@ -327,36 +395,17 @@ func (b *ugglyBrowser) sendMessage(msg, label string) {
 func (b *ugglyBrowser) settingsPage(infoMsg string) {
 	thisfunc := "settingsPage"
 	loggo.Info("building settings page")
+	if len(b.settingsWarnings) > 0 {
+		// so problems fixed up while loading don't go unnoticed
+		infoMsg = strings.TrimSpace(infoMsg + "\nSettings file problems: " +
+			strings.Join(b.settingsWarnings, "; "))
+	}
 	b.currentPage = buildSettings(b.vW, b.vH, b.settings, infoMsg)
 	b.currentPageLocal = b.currentPage
 	go b.sendMessage("Local Settings", thisfunc)
 	b.handle(b.buildDraw(thisfunc))
 }
 
-func (b *ugglyBrowser) bookmarksPage() {
-	thisfunc := "bookmarksPage"
-	loggo.Info("building bookmarks page")
-	b.currentPage = buildBookmarks(b.vW, b.vH, b.settings)
-	b.currentPageLocal = b.currentPage
-	go b.sendMessage("Bookmarks Browser", thisfunc)
-	b.handle(b.buildDraw(thisfunc))
-}
-
-func (b *ugglyBrowser) bookmarkAdd() {
-	thisfunc := "bookmarkAdd"
-	ugri := b.sess.genUgri()
-	b.settings.addBookmark("", *ugri)
-	loggo.Info("adding bookmark")
-	message := fmt.Sprintf("added bookmark: '%s'", *ugri)
-	go b.sendMessage(message, thisfunc)
-	err := b.settingsSave()
-	if err != nil {
-		loggo.Error("error adding bookmark", "err", err.Error())
-		message = "error adding bookmark, check log"
-		go b.sendMessage(message, thisfunc)
-	}
-}
-
 func (b *ugglyBrowser) colorDemo() {
 	thisfunc := "colorDemo"
 	b.currentPage = buildColorDemo(b.vW, b.vH)
@@ -365,9 +414,23 @@ func (b *ugglyBrowser) colorDemo() {
 	b.handle(b.buildDraw(thisfunc))
 }
 
+// exit shuts the browser down. Only the first caller gets to run it
+// since it closes channels, everyone else waits for os.Exit.
 func (b *ugglyBrowser) exit(code int) {
+	b.exitOnce.Do(func() { b.shutdown(code) })
+}
+
+func (b *ugglyBrowser) shutdown(code int) {
 	loggo.Info("caught exit interrupt", "code", code)
 	b.exitFlag = true // in case other go routines are watching
+	// cancel whatever request is in flight, cexVendor may be
+	// busy handing out a context so don't wait on it forever
+	select {
+	case b.cexCancel <- "exit":
+	case <-time.After(time.Second):
+		loggo.Info("cexVendor busy, not cancelling in-flight context")
+	}
+	b.sess.close()
 	err := b.storeCookies()
 	if err != nil {
 		loggo.Error("error storing cookies on close", "error", err.Error())
@@ -381,15 +444,40 @@ func (b *ugglyBrowser) exit(code int) {
 					" in the desired ENV var")
 		}
 	}
+	err = b.storeSession(true)
+	if err != nil {
+		loggo.Error("error storing session on close", "error", err.Error())
+	}
 	close(b.interrupt)
 	close(b.messageBuffer)
-	b.view.Fini()
+	if b.view != nil {
+		b.view.Fini()
+	}
 	for _, message := range b.exitMessages {
 		fmt.Println(message)
 	}
 	os.Exit(code)
 }
 
+// watchSignals shuts the browser down cleanly when the process is
+// told to stop so the terminal is restored and nothing is lost. Exit
+// codes follow the shell's 128+signal convention.
+func (b *ugglyBrowser) watchSignals() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	sig := <-sigs
+	loggo.Info("caught signal, shutting down", "signal", sig.String())
+	err := b.settingsSave()
+	if err != nil {
+		loggo.Error("error saving settings on signal", "error", err.Error())
+	}
+	code := 1
+	if s, ok := sig.(syscall.Signal); ok {
+		code = 128 + int(s)
+	}
+	b.exit(code)
+}
+
 func (b *ugglyBrowser) refresh(ctx context.Context) {
 	if b.currentPageLocal == nil {
 		partial := pb.Link{
@@ -409,12 +497,26 @@ func (b *ugglyBrowser) refresh(ctx context.Context) {
 			b.getFeed(ctx)
 		}
 		if b.currentPageLocal.Name == "uggcli-settings" {
-			b.settings = b.settingsLoad()
+			b.settingsMu.Lock()
+			b.settingsMerge()
+			b.settingsMu.Unlock()
 			b.settingsPage("")
 		}
 		if b.currentPageLocal.Name == "uggcli-bookmarks" {
 			b.bookmarksPage()
 		}
+		if b.currentPageLocal.Name == "uggcli-credentials" {
+			b.credentialsPage("")
+		}
+		if b.currentPageLocal.Name == "uggcli-cookies" {
+			b.cookiesPage("")
+		}
+		if b.currentPageLocal.Name == "uggcli-profiles" {
+			b.profilesPage("")
+		}
+		if b.currentPageLocal.Name == "uggcli-start" {
+			b.startPage("")
+		}
 	}
 }
 
@@ -444,21 +546,25 @@ func (b *ugglyBrowser) getFeed(ctx context.Context) {
 }
 
 func (b *ugglyBrowser) streamHandler(stream chan *pb.PageResponse) {
-	var ok bool
 	for {
 		select {
-		case b.currentPage, ok = <-stream:
+		case page, ok := <-stream:
 			if !ok {
 				stream = nil
 			} else {
 				loggo.Info("got page from stream, drawing...")
+				// overlays hold overlayMu, the frame waits until they close
+				b.overlayMu.Lock()
+				b.currentPage = page
 				b.setCookies(b.currentPage)
-				b.handle(b.buildDraw("get2"))
+				err := b.buildDraw("get2")
+				b.overlayMu.Unlock()
+				b.handle(err)
 				//time.Sleep(1*time.Millisecond)
-				if b.currentPage.StreamDelayMs == 0 {
+				if page.StreamDelayMs == 0 {
 					time.Sleep(500 * time.Millisecond)
 				} else {
-					s := time.Duration(b.currentPage.StreamDelayMs)
+					s := time.Duration(page.StreamDelayMs)
 					time.Sleep(s * time.Millisecond)
 				}
 			}
@@ -506,7 +612,16 @@ func (b *ugglyBrowser) cexVendor() {
 	}
 }
 
+// get2 requests a page the user asked for directly, e.g., from the
+// address bar, a bookmark or the home key
 func (b *ugglyBrowser) get2(ctx context.Context, pq *pb.PageRequest) {
+	b.get2From(ctx, pq, "")
+}
+
+// get2From requests a page on behalf of a page on the initiator server,
+// e.g., a followed link or a form submission, so SameSite cookies can
+// tell cross-site navigation apart
+func (b *ugglyBrowser) get2From(ctx context.Context, pq *pb.PageRequest, initiator string) {
 	var err error
 	pq.ClientWidth = int32(b.vW)
 	pq.ClientHeight = int32(b.vH)
@@ -519,7 +634,7 @@ func (b *ugglyBrowser) get2(ctx context.Context, pq *pb.PageRequest) {
 		go b.streamHandler(stream)
 		loggo.Info("requesting cancellable context from cexVendor")
 		b.cexJobs <- "stream"
-		ctxc, pqc := b.addCookies(<-b.cexOut, pq)
+		ctxc, pqc := b.addCookies(<-b.cexOut, pq, initiator)
 		go b.sendMessage("connected to stream!", "get2-stream-success")
 		b.currentPageLocal = nil // so refresh knows to get external
 		err = b.sess.getStream(ctxc, pqc, stream)
@@ -531,7 +646,7 @@ func (b *ugglyBrowser) get2(ctx context.Context, pq *pb.PageRequest) {
 	} else {
 		loggo.Info("requesting timeout context from cexVendor")
 		b.cexJobs <- "page"
-		ctxc, pqc := b.addCookies(<-b.cexOut, pq)
+		ctxc, pqc := b.addCookies(<-b.cexOut, pq, initiator)
 		b.currentPage, err = b.sess.get2(ctxc, pqc)
 	}
 	if err != nil {
@@ -551,6 +666,11 @@ func (b *ugglyBrowser) get2(ctx context.Context, pq *pb.PageRequest) {
 			msg := fmt.Sprintf("connection cancelled")
 			go b.sendMessage(msg, "get2-cancelled")
 			loggo.Error(msg)
+		} else if strings.Contains(err.Error(), errPinMismatch.Error()) {
+			msg := fmt.Sprintf("certificate for '%s' changed since this profile pinned it, "+
+				"not connecting (remove it from %s to trust the new one)", dest, pinsFileName)
+			go b.sendMessage(msg, "get2-pin")
+			loggo.Error(msg)
 		} else {
 			b.handle(err)
 		}
@@ -562,6 +682,8 @@ func (b *ugglyBrowser) get2(ctx context.Context, pq *pb.PageRequest) {
 			loggo.Debug("Got cookie from server", "key", setCookie.Key)
 		}
 		b.setCookies(b.currentPage)
+		b.bookmarkVisited()
+		b.recordHistory()
 		b.handle(b.buildDraw("get2"))
 	}
 }
@@ -613,53 +735,94 @@ func (b *ugglyBrowser) processFormSubmission(ctx context.Context, name string) {
 				// nefarious server re-using our sacred "uggcli-settings" form
 				loggo.Debug("detected settings submission")
 				b.settingsProcess(f.Collect())
+			} else if f.Name == fmt.Sprintf("uggcli-bookmarkexport-%s", localAuthUuid) {
+				b.bookmarkTransferProcess(true, f.Collect())
+			} else if f.Name == fmt.Sprintf("uggcli-bookmarkimport-%s", localAuthUuid) {
+				b.bookmarkTransferProcess(false, f.Collect())
+			} else if f.Name == fmt.Sprintf("uggcli-bookmarksearch-%s", localAuthUuid) {
+				b.bookmarkSearch(f.Collect())
+			} else if f.Name == fmt.Sprintf("uggcli-profilenew-%s", localAuthUuid) {
+				b.profileSwitch(strings.TrimSpace(f.Collect()["ProfileName"]))
+			} else if f.Name == fmt.Sprintf("uggcli-cookieexport-%s", localAuthUuid) {
+				b.cookieTransferProcess(true, f.Collect())
+			} else if f.Name == fmt.Sprintf("uggcli-cookieimport-%s", localAuthUuid) {
+				b.cookieTransferProcess(false, f.Collect())
 			} else {
-				// find form in current Page and
-				// discover submit link, collect
-				// contents from form and craft
-				// PageRequest with FormData
-				li := f.SubmitAction
-				loggo.Debug("got mainbody form submission link")
-				if li, ok := li.(*pb.Link); ok {
-					l, _ := b.linkFiller(li)
-					loggo.Debug("type assertion succeeded, getting link",
-						"pageName", l.PageName,
-						"server", l.Server,
-						"port", l.Port,
-					)
-					// convert link to PageRequest
-					pr := linkRequest(l)
-					// gather data from form and build request
-					data := f.Collect()
-					pr.FormData = []*pb.FormData{}
-					fd := &pb.FormData{
-						Name:        f.Name,
-						TextBoxData: []*pb.TextBoxData{},
-					}
-					for k, v := range data {
-						td := pb.TextBoxData{
-							Name:     k,
-							Contents: v,
-						}
-						fd.TextBoxData = append(
-							fd.TextBoxData, &td)
-					}
-					pr.FormData = append(pr.FormData, fd)
-					b.get2(ctx, pr)
-				}
+				b.submitPageForm(ctx, f.Name, f.SubmitAction, f.Collect())
 			}
 		}
 	}
+	for _, wf := range b.widgetForms {
+		if wf.Name == name {
+			b.submitPageForm(ctx, wf.Name, wf.SubmitAction, wf.Collect())
+		}
+	}
 }
 
-func (b *ugglyBrowser) formWatcher(ctx context.Context, interrupt chan struct{}, submit chan string) {
+// submitPageForm finds the form's submit link, collects contents
+// from the form and crafts a PageRequest with FormData
+func (b *ugglyBrowser) submitPageForm(ctx context.Context, name string, submitAction interface{}, data map[string]string) {
+	loggo.Debug("got mainbody form submission link")
+	if li, ok := submitAction.(*pb.Link); ok {
+		l, _ := b.linkFiller(li)
+		loggo.Debug("type assertion succeeded, getting link",
+			"pageName", l.PageName,
+			"server", l.Server,
+			"port", l.Port,
+		)
+		// convert link to PageRequest
+		pr := linkRequest(l)
+		// gather data from form and build request
+		pr.FormData = []*pb.FormData{}
+		fd := &pb.FormData{
+			Name:        name,
+			TextBoxData: []*pb.TextBoxData{},
+		}
+		for k, v := range data {
+			td := pb.TextBoxData{
+				Name:     k,
+				Contents: v,
+			}
+			fd.TextBoxData = append(
+				fd.TextBoxData, &td)
+		}
+		pr.FormData = append(pr.FormData, fd)
+		b.get2From(ctx, pr, b.pageInitiator())
+	}
+}
+
+// formWatcher waits for a polling form to finish. It always sends exactly
+// one verdict telling passForm whether the form needs to be polled again
+// because the submission failed validation.
+func (b *ugglyBrowser) formWatcher(ctx context.Context, name string, f pageForm, interrupt chan struct{}, submit chan string, verdict chan bool) {
 	for {
 		select {
 		case <-ctx.Done():
+			verdict <- false
 			return
 		case <-interrupt:
+			b.saveFormValues(name, f)
+			verdict <- false
 			return
 		case formName := <-submit:
+			if !b.validateForm(formName, f.Collect()) {
+				b.saveFormValues(name, f)
+				go b.sendMessage("please correct the highlighted fields", "form-validation")
+				verdict <- true
+				close(submit)
+				return
+			}
+			if !b.confirmSubmission(formName) {
+				b.saveFormValues(name, f)
+				go b.sendMessage("form submission cancelled", "form-confirm")
+				verdict <- false
+				close(submit)
+				return
+			}
+			// still own the screen so ask before handing back
+			b.offerSaveCredential(formName, f.Collect())
+			verdict <- false
+			b.clearFormValues(formName)
 			b.processFormSubmission(ctx, formName)
 			close(submit)
 			return
@@ -669,25 +832,53 @@ func (b *ugglyBrowser) formWatcher(ctx context.Context, interrupt chan struct{},
 
 // passForm takes a desired form name and then passes control over
 // to the form. This is a blocking function as it waits for the
-// passed form to close the interrupt channel.
+// passed form to close the interrupt channel. If the screen is
+// resized while the form has control the page is laid out again
+// and control is handed back to the rebuilt form with the user's
+// input intact.
 func (b *ugglyBrowser) passForm(ctx context.Context, name string) {
-	found := false
-	for _, f := range b.forms {
-		loggo.Info("checking all forms for desired form", "currForm", f.Name, "desiredName", name)
-		if f.Name == name && !found {
-			found = true
-			interrupt := make(chan struct{})
-			submit := make(chan string)
-			// ctx cancel() can be called to unblock
-			go b.formWatcher(ctx, interrupt, submit)
-			go f.Poll(ctx, interrupt, submit)
-			<-interrupt
-			// TODO: we were blocking during form Poll so
-			// we may need to update screen if it resized
-			// during form, trying to send to resizeBuffer
-			// made a weird bug
-			loggo.Debug("polling passed back to main")
+	key := formKey(name)
+	first := true
+	for {
+		fname, f := b.findForm(key)
+		if f == nil {
+			loggo.Info("could not find desired form", "desiredName", name)
+			return
 		}
+		if first {
+			first = false
+			if b.offerAutofill(fname) {
+				// form was rebuilt with the saved values
+				continue
+			}
+		}
+		loggo.Info("passing control to form", "formName", fname)
+		fctx, cancel := context.WithCancel(ctx)
+		interrupt := make(chan struct{})
+		submit := make(chan string)
+		resized := make(chan struct{}, 1)
+		verdict := make(chan bool, 1)
+		// ctx cancel() can be called to unblock
+		go b.formWatcher(fctx, fname, f, interrupt, submit, verdict)
+		go b.watchFormResize(fctx, cancel, resized)
+		go f.Poll(fctx, interrupt, submit)
+		<-interrupt
+		retry := <-verdict
+		cancel()
+		loggo.Debug("polling passed back to main")
+		select {
+		case <-resized:
+			b.saveFormValues(fname, f)
+			b.applySize()
+			b.refresh(ctx)
+			continue
+		default:
+		}
+		if !retry {
+			return
+		}
+		// give control straight back so the user can fix their input
+		loggo.Debug("form failed validation, passing control back", "formName", fname)
 	}
 }
 
@@ -704,6 +895,33 @@ func (b *ugglyBrowser) isLocal(link *pb.Link) bool {
 func (b *ugglyBrowser) localLinkRouter(link *pb.Link) {
 	if b.isLocal(link) { //double check
 		loggo.Info("processing local link")
+		if strings.HasPrefix(link.PageName, "cookie_") {
+			b.cookieLinkRouter(link.PageName)
+		}
+		if strings.HasPrefix(link.PageName, "bookmarks_") {
+			b.bookmarkLinkRouter(link.PageName)
+		}
+		if strings.HasPrefix(link.PageName, "profile_switch_") {
+			chunks := strings.Split(link.PageName, "_")
+			index, err := strconv.Atoi(chunks[2])
+			if err == nil && index >= 0 && index < len(b.profileList) {
+				b.profileSwitch(b.profileList[index])
+			}
+		}
+		if strings.Contains(link.PageName, "credential_delete") {
+			chunks := strings.Split(link.PageName, "_")
+			if len(chunks) > 2 {
+				index, err := strconv.Atoi(chunks[2])
+				infoMsg := "saved login deleted"
+				if err != nil || !b.deleteCredential(index) {
+					infoMsg = "saved login not deleted, could not find"
+				} else if err = b.storeCredentials(); err != nil {
+					infoMsg += ", error saving logins to disk"
+				}
+				b.sendMessage(infoMsg, "credential_delete")
+				b.credentialsPage(infoMsg)
+			}
+		}
 		if strings.Contains(link.PageName, "bookmark_delete") {
 			chunks := strings.Split(link.PageName, "_")
 			var bmUidString string
@@ -715,7 +933,9 @@ func (b *ugglyBrowser) localLinkRouter(link *pb.Link) {
 						"bmUidString", bmUidString)
 					b.sendMessage("error deleting bookmark", "bookmark_delete")
 				} else {
+					b.settingsMu.Lock()
 					ok := b.settings.deleteBookmark(bmUid)
+					b.settingsMu.Unlock()
 					if ok {
 						infoMsg := "bookmark deleted"
 						err = b.settingsSave()
@@ -744,7 +964,8 @@ func (b *ugglyBrowser) keyStrokeRouter(ctx context.Context, ks *pb.KeyStroke) {
 		} else {
 			loggo.Debug("keyStrokeRouter sending get2")
 			link, _ := b.linkFiller(x.Link)
-			go b.get2(ctx, linkRequest(link))
+			// grab the initiator now before the page changes under us
+			go b.get2From(ctx, linkRequest(link), b.pageInitiator())
 			localAuthUuid = uggo.NewUuid()
 		}
 	case *pb.KeyStroke_FormActivation:
@@ -758,35 +979,6 @@ func (b *ugglyBrowser) keyStrokeRouter(ctx context.Context, ks *pb.KeyStroke) {
 	}
 }
 
-func (b *ugglyBrowser) handleKeyStrokes(ctx context.Context, ev *tcell.EventKey) {
-	if ev.Key() == tcell.KeyRune {
-		loggo.Debug("detected keypress", "key", string(ev.Rune()))
-	} else {
-		_, name := detectSpecialKey(ev)
-		loggo.Debug("detected keypress", "key", name)
-	}
-	loggo.Debug("checking activeKeyStrokes for expected keypresses", "numLinks", len(b.activeKeyStrokes))
-	for _, ks := range b.activeKeyStrokes {
-		loggo.Debug("checking key", "expectedKey", ks.KeyStroke)
-		// see if we can detect a special
-		for k, v := range tcell.KeyNames {
-			if v == ks.KeyStroke {
-				if ev.Key() == k {
-					loggo.Info("sending expected key to keyStroke router")
-					b.keyStrokeRouter(ctx, ks)
-				}
-			}
-		}
-		// if not special then maybe a rune
-		if ev.Key() == tcell.KeyRune {
-			if ks.KeyStroke == string(ev.Rune()) {
-				b.keyStrokeRouter(ctx, ks)
-			}
-		}
-	}
-}
-
-
 func (b *ugglyBrowser) breaks(message string) {
 	if b.debugBreaks {
 		for {
@@ -810,39 +1002,19 @@ func (b *ugglyBrowser) pollEvents(ctx context.Context) {
 		ev := b.view.PollEvent()
 		switch ev := ev.(type) {
 		case *tcell.EventKey:
-			switch ev.Key() {
-			case tcell.KeyF10:
-				b.cexCancel <- "user-cancel"
-				b.exit(0)
-				return
-			case tcell.KeyCtrlL:
-				loggo.Info("kill context")
-				b.cexCancel <- "user-cancel"
-			case tcell.KeyF4:
-				b.cexCancel <- "user-cancel"
-				b.getFeed(ctx)
-			case tcell.KeyF2:
-				b.cexCancel <- "user-cancel"
-				b.colorDemo()
-			case tcell.KeyF3:
-				b.cexCancel <- "user-cancel"
-				b.settingsPage("")
-			case tcell.KeyF5:
-				b.cexCancel <- "user-cancel"
-				b.refresh(ctx)
-			case tcell.KeyF6:
-				b.cexCancel <- "user-cancel"
-				b.bookmarksPage()
-			case tcell.KeyF7:
-				b.cexCancel <- "user-cancel"
-				b.bookmarkAdd()
-			default:
-				loggo.Debug("sending to handleKeyStrokes",
-					"numLinks", len(b.activeKeyStrokes))
-				b.cexCancel <- "user-cancel"
-				b.handleKeyStrokes(ctx, ev)
-				// not async, poll could be blocked in handleKeyStrokes
+			if action := b.settings.keyAction(ev); action != "" {
+				// menu keys win, even in the middle of a chord
+				b.resetChord()
+				if !b.runAction(ctx, action) {
+					return
+				}
+				continue
 			}
+			loggo.Debug("sending to handleKeyStrokes",
+				"numLinks", len(b.activeKeyStrokes))
+			b.cexCancel <- "user-cancel"
+			b.handleKeyStrokes(ctx, ev)
+			// not async, poll could be blocked in handleKeyStrokes
 		case *tcell.EventResize:
 			b.view.Sync()
 			if !b.resizing {
@@ -851,6 +1023,8 @@ func (b *ugglyBrowser) pollEvents(ctx context.Context) {
 			}
 		case fakeEvent:
 			loggo.Debug("reloaded keyStrokes", "numKeyStrokes", len(b.activeKeyStrokes))
+		case chordTimeout:
+			b.chordExpired(ctx, ev.seq)
 		}
 	}
 }
@@ -966,14 +1140,20 @@ func (b *ugglyBrowser) resizeHandler(ctx context.Context) {
 	b.resizing = true
 	<-b.resizeBuffer
 	time.Sleep(b.resizeDelay)
+	b.applySize()
+	b.refresh(ctx)
+	//b.updateAll()
+	b.resizing = false
+}
+
+// applySize picks up the current screen dimensions so the
+// next refresh lays content out for them
+func (b *ugglyBrowser) applySize() {
 	w, h := b.view.Size()
 	b.sess.clientWidth = int32(w)
 	b.sess.clientHeight = int32(h)
 	b.vW = w
 	b.vH = h - b.menuHeight
-	b.refresh(ctx)
-	//b.updateAll()
-	b.resizing = false
 }
 
 func (b *ugglyBrowser) finalizeKeyStrokes() {
@@ -1019,6 +1199,12 @@ func (b *ugglyBrowser) parseKeyStrokes(page *pb.PageResponse, menu bool) {
 		}
 	}
 	b.finalizeKeyStrokes()
+	if !menu {
+		if conflicts := b.keyStrokeConflicts(); conflicts > 0 {
+			loggo.Info("page has keystrokes that won't fire, see help overlay",
+				"conflicts", conflicts)
+		}
+	}
 	loggo.Debug("parseKeyStrokes complete", "len(b.activeKeyStrokes)", len(b.activeKeyStrokes))
 }
 
@@ -1071,6 +1257,30 @@ func (b *ugglyBrowser) drawContent(label string) {
 	}
 	loggo.Debug("drawing all content", "len", len(content))
 	// now actually draw
+	b.drawBoxes(content)
+	// draw forms on top of canvas
+	for _, f := range b.forms {
+		loggo.Debug("starting form", "formName", f.Name)
+		f.Start()
+	}
+	for _, wf := range b.widgetForms {
+		loggo.Debug("starting widget form", "formName", wf.Name)
+		wf.Start()
+	}
+	b.drawFormErrors()
+	b.view.Show()
+	// collect some stats
+	dsForms := len(b.forms)
+	dsMenuBoxes := len(b.contentMenu)
+	dsExtBoxes := len(b.contentExt)
+	dsTotalBoxes := len(content)
+	loggo.Info("---------Draw Stats------------",
+		"forms", dsForms, "menuBoxes", dsMenuBoxes,
+		"extBoxes", dsExtBoxes, "totalBoxes", dsTotalBoxes)
+}
+
+// drawBoxes sets the screen contents for each box's pixels
+func (b *ugglyBrowser) drawBoxes(content []*boxes.DivBox) {
 	for _, bi := range content {
 		for i := 0; i < bi.Width; i++ {
 			for j := 0; j < bi.Height; j++ {
@@ -1086,26 +1296,17 @@ func (b *ugglyBrowser) drawContent(label string) {
 			}
 		}
 	}
-	// draw forms on top of canvas
-	for _, f := range b.forms {
-		loggo.Debug("starting form", "formName", f.Name)
-		f.Start()
-	}
-	b.view.Show()
-	// collect some stats
-	dsForms := len(b.forms)
-	dsMenuBoxes := len(b.contentMenu)
-	dsExtBoxes := len(b.contentExt)
-	dsTotalBoxes := len(content)
-	loggo.Info("---------Draw Stats------------",
-		"forms", dsForms, "menuBoxes", dsMenuBoxes,
-		"extBoxes", dsExtBoxes, "totalBoxes", dsTotalBoxes)
 }
 
 type ugglyBrowser struct {
 	view             tcell.Screen
 	contentMenu      []*boxes.DivBox
 	forms            []*ugform.Form  // stores forms known at this time
+	widgetForms      []*widgets.Form        // page forms with client side widgets
+	formSources      map[string]*formSource // page forms by form name
+	formSourcesMu    sync.Mutex             // guards formSources, read by form goroutines
+	formState        *formState             // in-progress form values per page
+	formErrors       *formErrors            // validation messages per form
 	menuForms        []*ugform.Form  // stores menuforms known at this time
 	contentExt       []*boxes.DivBox // e.g., non-menu content
 	currentPage      *pb.PageResponse
@@ -1119,14 +1320,47 @@ type ugglyBrowser struct {
 	resizeDelay      time.Duration
 	activeKeyStrokes []*pb.KeyStroke
 	menuKeyStrokes   []*pb.KeyStroke
-	cookies          map[string][]*pb.Cookie // all cookies stored for each server string
+	cookies          *cookiejar.Jar          // all cookies stored for each server string
+	cookiePageServers []string               // server order shown on the cookie manager
+	cookiePagePending []string               // pending server:port order shown on the cookie manager
+	pendingCookies   map[string]*heldCookies // cookies held per server:port until the user picks a policy
+	pendingMu        sync.Mutex              // guards pendingCookies, set from page goroutines
+	cookieSaves      chan struct{}           // requests a background save of the cookie jar
+	cookieSaveDelay  time.Duration           // how long to coalesce save requests
+	cookieSaveMu     sync.Mutex              // one vault write at a time
+	savedCookies     string                  // jar contents as of the last save
+	credentials      []*credential           // saved logins
+	autofillDeclined map[string]bool         // page+form keys the user didn't want filled
+	pins             *pinStore               // TLS certificates pinned by this profile
 	menuHeight       int
 	exitFlag         bool
+	overlayMu        sync.Mutex // held while an overlay owns the screen, stream frames wait
+	exitOnce         sync.Once
 	vH               int      // view height (updates on resize event)
 	vW               int      // view width (updates on resize event)
 	exitMessages     []string // messages to print on exit since stdout no worky during
 	settings         *ugglyBrowserSettings
 	settingsFile	 string
+	bookmarkFolder   string          // folder being browsed on the bookmarks page
+	bookmarkQuery    string          // search filtering the bookmarks page
+	bookmarkOffset   int             // first entry shown on the bookmarks page
+	bookmarkList     []bookmarkEntry // entry order shown on the bookmarks page
+	history          []*historyEntry // recently loaded pages, newest first
+	sessionMu        sync.Mutex      // one session file write at a time
+	savedSession     []byte          // session as of the last write
+	sessionPoll      time.Duration   // how often the session is saved for crash recovery
+	chordPending     []keySpec       // keys typed so far of a multi-key keystroke
+	chordComplete    []*pb.KeyStroke // keystrokes to route if the chord times out
+	chordSeq         int             // tells a stale chordTimeout from the current one
+	chordDelay       time.Duration   // how long a chord waits for its next key
+	profile          string        // "" is the default profile
+	profileList      []string      // profile order shown on the profiles page
+	settingsWarnings []string      // problems found the last time settings were loaded
+	settingsClashes  []string      // fields changed both on disk and in memory
+	settingsBase     []byte        // settings as last read from or written to disk
+	settingsModTime  time.Time     // settings file's mtime when last read or written
+	settingsMu       sync.Mutex    // guards b.settings, serializes saves and reloads
+	settingsPoll     time.Duration // how often to check the settings file for changes
 	vaultPassEnvVar  string
 	// define channels for context vendor
 	cexCancel, cexJobs chan string
@@ -1150,7 +1384,21 @@ func newBrowser() *ugglyBrowser {
 	b.contentExt = make([]*boxes.DivBox, 0)
 	b.currentPage = &pb.PageResponse{}
 	b.activeKeyStrokes = make([]*pb.KeyStroke, 0)
-	b.cookies = make(map[string][]*pb.Cookie, 0)
+	b.cookies = cookiejar.New()
+	b.pendingCookies = make(map[string]*heldCookies)
+	b.pins = newPinStore()
+	b.cookieSaves = make(chan struct{}, 1)
+	b.cookieSaveDelay = 2 * time.Second
+	b.settingsPoll = 2 * time.Second
+	b.sessionPoll = 10 * time.Second
+	b.chordDelay = time.Second
+	b.credentials = make([]*credential, 0)
+	b.history = make([]*historyEntry, 0)
+	b.autofillDeclined = make(map[string]bool)
+	b.widgetForms = make([]*widgets.Form, 0)
+	b.formSources = make(map[string]*formSource)
+	b.formState = newFormState()
+	b.formErrors = newFormErrors()
 	b.exitMessages = make([]string, 0)
 	b.cexJobs = make(chan string)
 	b.cexCancel = make(chan string)
@@ -1165,12 +1413,30 @@ func (b *ugglyBrowser) start(ugri string) (err error) {
 	if err != nil {
 		return err
 	}
+	go b.watchSignals()
+	go b.settingsWatcher()
 	err = b.loadCookies()
 	if err != nil {
 		loggo.Error("error loading cookies from file", "error", err.Error())
 		// not fatal so we'll continue
 		err = nil
 	}
+	go b.cookieSaver()
+	err = b.loadCredentials()
+	if err != nil {
+		loggo.Error("error loading saved logins from file", "error", err.Error())
+		err = nil
+	}
+	err = b.loadHistory()
+	if err != nil {
+		loggo.Info("no history loaded", "error", err.Error())
+		err = nil
+	}
+	err = b.pins.load(b.pinsFile())
+	if err != nil {
+		loggo.Info("no certificate pins loaded", "error", err.Error())
+		err = nil
+	}
 	w, h := b.view.Size()
 	b.vW = w
 	b.vH = h - b.menuHeight
@@ -1179,6 +1445,9 @@ func (b *ugglyBrowser) start(ugri string) (err error) {
 	go b.cexVendor()
 	b.cexJobs <- "page"
 	ctx := <-b.cexOut
+	// ask about restoring before anything else polls the screen
+	restore := b.sessionToRestore(ugri != "")
+	go b.sessionSaver()
 	// start main event poller for keyboard activity
 	go b.pollEvents(ctx)
 	// start menu watcher which looks for messages to be
@@ -1188,21 +1457,16 @@ func (b *ugglyBrowser) start(ugri string) (err error) {
 	// draw a blank page with menu to start
 	loggo.Info("building menu content")
 	if ugri != "" {
-		// build a local link as a bootstrap since
-		// no server can send us any links yet
-		startLink, _ := linkFromString(ugri)
-		b.sess.server = startLink.Server
-		b.sess.port = startLink.Port
-		b.sess.secure = startLink.Secure
-		b.sess.currPage = startLink.PageName
-		b.sess.stream = startLink.Stream
-		// try to get initial link from a server
-		loggo.Info("getting page from server")
-		startLink, _ = b.linkFiller(startLink)
-		b.get2(ctx, linkRequest(startLink))
+		err = b.openUgri(ctx, ugri)
+		if err != nil {
+			loggo.Error("error parsing start UGRI", "ugri", ugri, "error", err.Error())
+			b.startPage(fmt.Sprintf("could not open '%s', it is not a valid ugri", ugri))
+			err = nil
+		}
+	} else if restore != nil {
+		b.restoreSession(ctx, restore)
 	} else {
-		loggo.Info("no start link, starting blank")
-		go b.sendMessage("enter an address with F1", "start-blank")
+		b.startup(ctx)
 	}
 	//b.buildContentMenu("init")
 	// start something that watches for exit
@@ -1236,7 +1500,15 @@ func main() {
 	// control over screen and when stdout is accessed at
 	// the same time, weird things happen
 	daemonFlag := true
-	setLogger(daemonFlag, logFile, *logLevel)
+	if !validProfileName(*profile) {
+		fmt.Fprintln(os.Stderr, "profile names can only use letters, numbers, '-' and '_'")
+		os.Exit(1)
+	}
+	config, vault, logPath, moved := browserPaths(*profile, *configFile, *vaultFile, *logFile)
+	setLogger(daemonFlag, logPath, *logLevel)
+	for _, msg := range moved {
+		loggo.Info("migrating files to XDG directories", "result", msg)
+	}
 	if version == "" {
 		version = "0.0.0"
 	}
@@ -1247,6 +1519,7 @@ func main() {
 	boxes.Loggo = loggo
 	ugform.Loggo = loggo
 	ugcon.Loggo = loggo
+	widgets.Loggo = loggo
 	uggsec.Loggo = loggo
 
 	if *genPass {
@@ -1256,16 +1529,28 @@ func main() {
 	brow = newBrowser()
 	brow.debugBreaks = *breaks
 	var err error
-	brow.settingsFile = *configFile
+	brow.profile = *profile
+	brow.settingsFile = config
 	brow.settings = brow.settingsLoad()
 	// check to see if we need to override loaded config with any params
 	if *vaultEnvVar != "UGGSECP" {
 		brow.settings.VaultPassEnvVar = vaultEnvVar
 	}
-	if *vaultFile != "cookies.json.encrypted" {
-		brow.settings.VaultFile = vaultFile
+	if *vaultFile != "" {
+		brow.settings.VaultFile = &vault
 	}
+	brow.exitMessages = append(brow.exitMessages, moved...)
 	brow.sess = newSession()
+	brow.sess.pins = brow.pins
+	if *vaultRotate {
+		os.Exit(brow.rotateVault())
+	}
+	if *cookiesExport != "" || *cookiesImport != "" {
+		os.Exit(brow.cookieTransfer(*cookiesExport, *cookiesImport, *cookiesServers))
+	}
+	if *bookmarksExport != "" || *bookmarksImport != "" {
+		os.Exit(brow.bookmarkTransfer(*bookmarksExport, *bookmarksImport, *bookmarksFolder))
+	}
 	// start the monostruct
 	err = brow.start(*ugri)
 	defer brow.view.Fini()
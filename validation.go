@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rendicott/uggly-client/ugcon"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fieldRules are the validation field hints a server attached to a
+// textbox, e.g., "Age {{required;min=0;max=130}}". Supported hints are
+// required, maxlen=<n>, pattern=<regex>, min=<number> and max=<number>.
+// A pattern has to match the whole value, like HTML's pattern attribute.
+type fieldRules struct {
+	required bool
+	maxLen   int
+	pattern  *regexp.Regexp
+	min, max *float64
+}
+
+// parseFieldRules builds rules from field hints. Hints that can't be
+// parsed are logged and skipped so a typo on the server doesn't make
+// the form impossible to submit.
+func parseFieldRules(name string, hints map[string]string) *fieldRules {
+	r := fieldRules{}
+	if _, ok := hints["required"]; ok {
+		r.required = true
+	}
+	if v, ok := hints["maxlen"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			loggo.Info("ignoring bad maxlen hint", "field", name, "maxlen", v)
+		} else {
+			r.maxLen = n
+		}
+	}
+	if v, ok := hints["pattern"]; ok {
+		re, err := regexp.Compile("^(?:" + v + ")$")
+		if err != nil {
+			loggo.Info("ignoring bad pattern hint", "field", name,
+				"pattern", v, "err", err.Error())
+		} else {
+			r.pattern = re
+		}
+	}
+	for _, bound := range []string{"min", "max"} {
+		v, ok := hints[bound]
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			loggo.Info("ignoring bad range hint", "field", name, bound, v)
+			continue
+		}
+		if bound == "min" {
+			r.min = &f
+		} else {
+			r.max = &f
+		}
+	}
+	return &r
+}
+
+// check returns a short message describing why value breaks the
+// rules or an empty string if it's fine
+func (r *fieldRules) check(value string) string {
+	if value == "" {
+		if r.required {
+			return "required"
+		}
+		return ""
+	}
+	if r.maxLen > 0 && len([]rune(value)) > r.maxLen {
+		return fmt.Sprintf("max %d chars", r.maxLen)
+	}
+	if r.pattern != nil && !r.pattern.MatchString(value) {
+		return "invalid format"
+	}
+	if r.min != nil || r.max != nil {
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return "must be a number"
+		}
+		if r.min != nil && n < *r.min {
+			return fmt.Sprintf("min %s", strconv.FormatFloat(*r.min, 'f', -1, 64))
+		}
+		if r.max != nil && n > *r.max {
+			return fmt.Sprintf("max %s", strconv.FormatFloat(*r.max, 'f', -1, 64))
+		}
+	}
+	return ""
+}
+
+// formErrors holds the validation messages for each form by field
+// name so they can be drawn next to the offending boxes on every
+// redraw until the form is submitted successfully
+type formErrors struct {
+	mu    sync.Mutex
+	forms map[string]map[string]string
+}
+
+func newFormErrors() *formErrors {
+	return &formErrors{forms: make(map[string]map[string]string)}
+}
+
+func (fe *formErrors) set(pageKey, formName string, problems map[string]string) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	key := pageKey + formKey(formName)
+	if len(problems) == 0 {
+		delete(fe.forms, key)
+		return
+	}
+	fe.forms[key] = problems
+}
+
+func (fe *formErrors) get(pageKey, formName string) map[string]string {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	return fe.forms[pageKey+formKey(formName)]
+}
+
+// validateForm checks the collected values of a page form against the
+// field hints its textboxes were sent with. Problems are remembered
+// and drawn next to each box. Returns true if the form can be sent.
+func (b *ugglyBrowser) validateForm(name string, values map[string]string) bool {
+	src, ok := b.lookupFormSource(name)
+	if !ok {
+		// menu and unknown forms have nothing to check
+		return true
+	}
+	problems := make(map[string]string)
+	for _, tb := range src.form.TextBoxes {
+		_, hints := ugcon.ParseFieldHints(tb.Description)
+		value := values[tb.Name]
+		if hints["widget"] == ugcon.WidgetCheckbox && value == "false" {
+			// so required means the box has to be checked
+			value = ""
+		}
+		msg := parseFieldRules(tb.Name, hints).check(value)
+		if msg != "" {
+			problems[tb.Name] = msg
+		}
+	}
+	b.formErrors.set(src.pageKey, name, problems)
+	if len(problems) > 0 {
+		loggo.Info("form failed validation", "form", name, "problems", len(problems))
+		b.drawFormErrors()
+		b.view.Show()
+		return false
+	}
+	return true
+}
+
+// drawFormErrors writes any validation messages for the current
+// page's forms just to the right of the offending boxes
+func (b *ugglyBrowser) drawFormErrors() {
+	st := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorRed)
+	w, _ := b.view.Size()
+	for name, src := range b.currentFormSources() {
+		problems := b.formErrors.get(src.pageKey, name)
+		for _, tb := range src.form.TextBoxes {
+			msg, ok := problems[tb.Name]
+			if !ok {
+				continue
+			}
+			x := src.shiftX + int(tb.PositionX+tb.Width) + 1
+			y := src.shiftY + int(tb.PositionY)
+			for i, c := range []rune(" " + msg + " ") {
+				if x+i >= w {
+					break
+				}
+				b.view.SetContent(x+i, y, c, nil, st)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/rendicott/uggly-client/ugcon"
+	"testing"
+)
+
+func TestFieldRulesCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		desc  string
+		value string
+		want  string
+	}{
+		{"no rules", "Name", "anything", ""},
+		{"required and blank", "Name {{required}}", "", "required"},
+		{"required and filled", "Name {{required}}", "bob", ""},
+		{"blank but optional", "Zip {{pattern=[0-9]{5}}}", "", ""},
+		{"maxlen", "Code {{maxlen=3}}", "abcd", "max 3 chars"},
+		{"maxlen counts runes", "Code {{maxlen=3}}", "äöü", ""},
+		{"pattern matches", "Zip {{pattern=[0-9]{5}}}", "12345", ""},
+		{"pattern only inside", "Zip {{pattern=[0-9]{5}}}", "123456", "invalid format"},
+		{"pattern with text around", "Zip {{pattern=[0-9]{5}}}", "zip 12345", "invalid format"},
+		{"pattern alternation anchored", "Pet {{pattern=cat|dog}}", "cats", "invalid format"},
+		{"pattern alternation", "Pet {{pattern=cat|dog}}", "dog", ""},
+		{"escaped separator in pattern", `Pair {{pattern=a\;b;required}}`, "a;b", ""},
+		{"bad pattern ignored", "Code {{pattern=[}}", "x", ""},
+		{"not a number", "Age {{min=0}}", "old", "must be a number"},
+		{"below min", "Age {{min=0;max=130}}", "-1", "min 0"},
+		{"above max", "Age {{min=0;max=130}}", "131", "max 130"},
+		{"in range", "Age {{min=0;max=130}}", " 42 ", ""},
+		{"fractional bound", "Ratio {{max=0.5}}", "0.75", "max 0.5"},
+		{"bad bound ignored", "Age {{min=zero}}", "old", ""},
+	}
+	for _, tt := range tests {
+		_, hints := ugcon.ParseFieldHints(tt.desc)
+		if got := parseFieldRules("field", hints).check(tt.value); got != tt.want {
+			t.Errorf("%s: check(%q) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
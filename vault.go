@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"github.com/rendicott/uggsec"
+	"github.com/zalando/go-keyring"
+	"os"
+)
+
+// vaultFiles returns the encrypted files sharing the vault key that
+// exist on disk
+func (b *ugglyBrowser) vaultFiles() []string {
+	files := []string{}
+	for _, f := range []string{*b.settings.VaultFile, b.credentialFile()} {
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// rotateEnvVar carries the new key to uggsec while rotating so the new
+// files can be written and checked before the key store changes
+const rotateEnvVar = "UGGLYC_VAULT_ROTATE"
+
+// openRotated opens filename with the new key alone, never the keyring
+// or the user's ENV var
+func openRotated(filename string) (*uggsec.Vault, error) {
+	return uggsec.InitSmart(&uggsec.VaultInput{
+		Filename:       filename,
+		PasswordEnvVar: rotateEnvVar,
+	})
+}
+
+// rotateVault runs the -vault-rotate mode. Every vault file is decrypted
+// with the current key before anything is touched, then re-encrypted
+// with a new key into a new file next to it which is opened again with
+// the new key to verify it. Only then does the new key replace the old
+// one in the OS keyring, or in the ENV var for systems without a
+// keyring, and the files are checked once more the way the browser opens
+// them before they replace the originals. The originals are kept as
+// backups until the end and everything is put back if any step fails.
+// Returns the exit code.
+func (b *ugglyBrowser) rotateVault() int {
+	files := b.vaultFiles()
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "no vault found at '%s', nothing to rotate\n",
+			*b.settings.VaultFile)
+		return 1
+	}
+	contents := make(map[string]string)
+	for _, f := range files {
+		c, err := b.readVault(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error decrypting '%s' with the current vault key: %s\n"+
+				"The key is read from the OS keyring or the %s ENV var. If it is "+
+				"missing the vault can't be decrypted and has to be deleted.\n",
+				f, err.Error(), *b.settings.VaultPassEnvVar)
+			return 1
+		}
+		contents[f] = c
+	}
+	// pick the key store the same way loadVault does so a keyring that
+	// fails for some other reason isn't mistaken for ENV var mode
+	_, err := uggsec.InitSmart(&uggsec.VaultInput{
+		Filename: files[0],
+		Service:  vaultService,
+		User:     vaultUser,
+	})
+	useKeyring := err == nil
+	envVar := *b.settings.VaultPassEnvVar
+	oldEnv := os.Getenv(envVar)
+	var oldPass string
+	if useKeyring {
+		oldPass, err = keyring.Get(vaultService, vaultUser)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading the current key from the keyring: %s\n"+
+				"nothing was changed\n", err.Error())
+			return 1
+		}
+	}
+	newPass := uggsec.NewVaultPassword()
+	os.Setenv(rotateEnvVar, newPass)
+	defer os.Unsetenv(rotateEnvVar)
+	rotated := func(f string) string { return f + ".rotate" }
+	backup := func(f string) string { return f + ".bak" }
+	removeRotated := func() {
+		for _, f := range files {
+			os.Remove(rotated(f))
+		}
+	}
+	fail := func(reason string, err error) int {
+		loggo.Error("vault rotation failed", "reason", reason, "err", err.Error())
+		fmt.Fprintf(os.Stderr, "error %s: %s\nthe vault was left as it was\n",
+			reason, err.Error())
+		return 1
+	}
+	for _, f := range files {
+		vault, err := openRotated(rotated(f))
+		if err == nil {
+			err = vault.Write(contents[f])
+		}
+		if err != nil {
+			removeRotated()
+			return fail(fmt.Sprintf("re-encrypting '%s'", f), err)
+		}
+	}
+	// a fresh vault with nothing but the new key has to read them back
+	// before the old key is given up
+	verify := func(open func(string) (*uggsec.Vault, error)) error {
+		for _, f := range files {
+			vault, err := open(rotated(f))
+			if err != nil {
+				return err
+			}
+			got, err := vault.Read()
+			if err != nil {
+				return err
+			}
+			if got != contents[f] {
+				return fmt.Errorf("contents of '%s' changed", f)
+			}
+		}
+		return nil
+	}
+	if err = verify(openRotated); err != nil {
+		removeRotated()
+		return fail("verifying the re-encrypted files with the new key", err)
+	}
+	restoreKey := func() {
+		if !useKeyring {
+			os.Setenv(envVar, oldEnv)
+			return
+		}
+		if kerr := keyring.Set(vaultService, vaultUser, oldPass); kerr != nil {
+			fmt.Fprintf(os.Stderr, "error restoring the old key to the keyring: %s\n",
+				kerr.Error())
+		}
+	}
+	if useKeyring {
+		err = keyring.Set(vaultService, vaultUser, newPass)
+		if err != nil {
+			restoreKey()
+			removeRotated()
+			return fail("storing the new key in the keyring", err)
+		}
+	} else {
+		os.Setenv(envVar, newPass)
+	}
+	// and the browser has to find the new key where it looks for it
+	if err = verify(b.loadVault); err != nil {
+		restoreKey()
+		removeRotated()
+		return fail("verifying the re-encrypted files with the stored key", err)
+	}
+	// swap the files in keeping the originals until the very end
+	swapped := []string{}
+	rollback := func(reason string, err error) int {
+		restoreKey()
+		for _, f := range swapped {
+			os.Rename(backup(f), f)
+		}
+		removeRotated()
+		return fail(reason, err)
+	}
+	for _, f := range files {
+		if err = os.Rename(f, backup(f)); err != nil {
+			return rollback(fmt.Sprintf("backing up '%s'", f), err)
+		}
+		swapped = append(swapped, f)
+		if err = os.Rename(rotated(f), f); err != nil {
+			return rollback(fmt.Sprintf("replacing '%s'", f), err)
+		}
+	}
+	for _, f := range files {
+		got, err := b.readVault(f)
+		if err == nil && got != contents[f] {
+			err = fmt.Errorf("contents changed")
+		}
+		if err != nil {
+			return rollback(fmt.Sprintf("verifying '%s'", f), err)
+		}
+	}
+	for _, f := range files {
+		os.Remove(backup(f))
+	}
+	loggo.Info("rotated vault key", "files", len(files), "keyring", useKeyring)
+	for _, f := range files {
+		fmt.Printf("re-encrypted '%s'\n", f)
+	}
+	if useKeyring {
+		fmt.Println("the new key is stored in the OS keyring")
+	} else {
+		fmt.Printf("no OS keyring available, update the %s ENV var with the "+
+			"new key before starting the browser:\n\nexport %s=%s\n",
+			envVar, envVar, newPass)
+	}
+	return 0
+}
@@ -0,0 +1,376 @@
+package widgets
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"strings"
+)
+
+// Common lets every widget embedding an Input satisfy Widget
+func (in *Input) Common() *Input {
+	return in
+}
+
+// Checkbox toggles between checked and unchecked with Space.
+// Its value is "true" or "false".
+type Checkbox struct {
+	*Input
+	Checked bool
+}
+
+func NewCheckbox(in *Input, checked bool) *Checkbox {
+	return &Checkbox{Input: in, Checked: checked}
+}
+
+func (c *Checkbox) Value() string {
+	if c.Checked {
+		return "true"
+	}
+	return "false"
+}
+
+func (c *Checkbox) Draw(s tcell.Screen, focused bool) {
+	box := "[ ]"
+	if c.Checked {
+		box = "[x]"
+	}
+	st := c.Styles.Text
+	if focused {
+		st = c.Styles.Cursor
+	}
+	drawText(s, c.PositionX, c.PositionY, len(box), box, st)
+	c.drawDescription(s)
+}
+
+func (c *Checkbox) HandleKey(ev *tcell.EventKey) bool {
+	if ev.Key() == tcell.KeyRune && ev.Rune() == ' ' {
+		c.Checked = !c.Checked
+		return true
+	}
+	return false
+}
+
+// RadioGroup draws one line per option and allows exactly one
+// to be selected. Up/Down move the cursor and Space selects.
+type RadioGroup struct {
+	*Input
+	Options  []string
+	selected int
+	cursor   int
+}
+
+func NewRadioGroup(in *Input, options []string, value string) *RadioGroup {
+	r := &RadioGroup{Input: in, Options: options}
+	r.selected = indexOf(options, value)
+	r.cursor = r.selected
+	return r
+}
+
+func (r *RadioGroup) Value() string {
+	if len(r.Options) == 0 {
+		return ""
+	}
+	return r.Options[r.selected]
+}
+
+func (r *RadioGroup) Draw(s tcell.Screen, focused bool) {
+	for i, opt := range r.Options {
+		mark := "( ) "
+		if i == r.selected {
+			mark = "(*) "
+		}
+		st := r.Styles.Text
+		if focused && i == r.cursor {
+			st = r.Styles.Cursor
+		}
+		drawText(s, r.PositionX, r.PositionY+i, r.Width, mark+opt, st)
+	}
+	r.drawDescription(s)
+}
+
+func (r *RadioGroup) HandleKey(ev *tcell.EventKey) bool {
+	if len(r.Options) == 0 {
+		return false
+	}
+	switch ev.Key() {
+	case tcell.KeyUp:
+		if r.cursor > 0 {
+			r.cursor--
+		}
+		return true
+	case tcell.KeyDown:
+		if r.cursor < len(r.Options)-1 {
+			r.cursor++
+		}
+		return true
+	case tcell.KeyRune:
+		if ev.Rune() == ' ' {
+			r.selected = r.cursor
+			return true
+		}
+	}
+	return false
+}
+
+// Select is a single line dropdown. Left/Right cycle through the
+// options in place, Space opens the list below the widget where
+// Up/Down and Enter pick an option and Escape closes it again.
+type Select struct {
+	*Input
+	Options  []string
+	selected int
+	hover    int
+	open     bool
+	repaint  bool
+}
+
+func NewSelect(in *Input, options []string, value string) *Select {
+	sl := &Select{Input: in, Options: options}
+	sl.selected = indexOf(options, value)
+	return sl
+}
+
+func (sl *Select) Value() string {
+	if len(sl.Options) == 0 {
+		return ""
+	}
+	return sl.Options[sl.selected]
+}
+
+func (sl *Select) Draw(s tcell.Screen, focused bool) {
+	st := sl.Styles.Fill
+	if focused && !sl.open {
+		st = sl.Styles.Cursor
+	}
+	width := sl.Width
+	if width < 4 {
+		width = 4
+	}
+	label := []rune(sl.Value())
+	if len(label) > width-2 {
+		label = label[:width-2]
+	}
+	drawText(s, sl.PositionX, sl.PositionY, width-1, string(label), st)
+	s.SetContent(sl.PositionX+width-1, sl.PositionY, 'v', nil, st)
+	sl.drawDescription(s)
+	if !sl.open {
+		return
+	}
+	for i, opt := range sl.Options {
+		ost := sl.Styles.Text
+		if i == sl.hover {
+			ost = sl.Styles.Cursor
+		}
+		drawText(s, sl.PositionX, sl.PositionY+1+i, width, opt, ost)
+	}
+}
+
+func (sl *Select) HandleKey(ev *tcell.EventKey) bool {
+	if len(sl.Options) == 0 {
+		// nothing to choose from
+		return false
+	}
+	if sl.open {
+		switch ev.Key() {
+		case tcell.KeyUp:
+			if sl.hover > 0 {
+				sl.hover--
+			}
+		case tcell.KeyDown:
+			if sl.hover < len(sl.Options)-1 {
+				sl.hover++
+			}
+		case tcell.KeyEnter:
+			sl.selected = sl.hover
+			sl.close()
+		case tcell.KeyEscape:
+			sl.close()
+		case tcell.KeyRune:
+			if ev.Rune() == ' ' {
+				sl.selected = sl.hover
+				sl.close()
+			}
+		}
+		// everything is swallowed while the list is open
+		return true
+	}
+	switch ev.Key() {
+	case tcell.KeyLeft, tcell.KeyUp:
+		sl.selected = (sl.selected - 1 + len(sl.Options)) % len(sl.Options)
+		return true
+	case tcell.KeyRight, tcell.KeyDown:
+		sl.selected = (sl.selected + 1) % len(sl.Options)
+		return true
+	case tcell.KeyRune:
+		if ev.Rune() == ' ' {
+			sl.open = true
+			sl.hover = sl.selected
+			return true
+		}
+	}
+	return false
+}
+
+func (sl *Select) close() {
+	sl.open = false
+	sl.repaint = true
+}
+
+func (sl *Select) takeRepaint() bool {
+	r := sl.repaint
+	sl.repaint = false
+	return r
+}
+
+// TextArea is an editable block of text. With a Height of one it
+// behaves like a plain textbox and Enter submits the form, otherwise
+// Enter starts a new line and Ctrl-D submits.
+type TextArea struct {
+	*Input
+	Password bool
+	lines    [][]rune
+	row      int
+	col      int
+	top      int
+	left     int
+}
+
+func NewTextArea(in *Input, value string, password bool) *TextArea {
+	t := &TextArea{Input: in, Password: password}
+	for _, line := range strings.Split(value, "\n") {
+		t.lines = append(t.lines, []rune(line))
+	}
+	if t.Height < 1 {
+		t.Height = 1
+	}
+	return t
+}
+
+func (t *TextArea) Value() string {
+	lines := make([]string, len(t.lines))
+	for i, line := range t.lines {
+		lines[i] = string(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// scroll keeps the cursor inside the visible part of the area
+func (t *TextArea) scroll() {
+	if t.row < t.top {
+		t.top = t.row
+	}
+	if t.row >= t.top+t.Height {
+		t.top = t.row - t.Height + 1
+	}
+	if t.col < t.left {
+		t.left = t.col
+	}
+	if t.col >= t.left+t.Width {
+		t.left = t.col - t.Width + 1
+	}
+}
+
+func (t *TextArea) Draw(s tcell.Screen, focused bool) {
+	t.scroll()
+	for i := 0; i < t.Height; i++ {
+		var line []rune
+		if t.top+i < len(t.lines) {
+			line = t.lines[t.top+i]
+		}
+		for j := 0; j < t.Width; j++ {
+			c := ' '
+			st := t.Styles.Fill
+			if t.left+j < len(line) {
+				c = line[t.left+j]
+				st = t.Styles.Text
+				if t.Password {
+					c = '*'
+				}
+			}
+			if focused && t.top+i == t.row && t.left+j == t.col {
+				st = t.Styles.Cursor
+			}
+			s.SetContent(t.PositionX+j, t.PositionY+i, c, nil, st)
+		}
+	}
+	t.drawDescription(s)
+}
+
+func (t *TextArea) HandleKey(ev *tcell.EventKey) bool {
+	line := t.lines[t.row]
+	switch ev.Key() {
+	case tcell.KeyRune:
+		line = append(line[:t.col], append([]rune{ev.Rune()}, line[t.col:]...)...)
+		t.lines[t.row] = line
+		t.col++
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if t.col > 0 {
+			t.lines[t.row] = append(line[:t.col-1], line[t.col:]...)
+			t.col--
+		} else if t.row > 0 {
+			// join with the line above
+			prev := t.lines[t.row-1]
+			t.col = len(prev)
+			t.lines[t.row-1] = append(prev, line...)
+			t.lines = append(t.lines[:t.row], t.lines[t.row+1:]...)
+			t.row--
+		}
+	case tcell.KeyDelete:
+		if t.col < len(line) {
+			t.lines[t.row] = append(line[:t.col], line[t.col+1:]...)
+		} else if t.row < len(t.lines)-1 {
+			t.lines[t.row] = append(line, t.lines[t.row+1]...)
+			t.lines = append(t.lines[:t.row+1], t.lines[t.row+2:]...)
+		}
+	case tcell.KeyLeft:
+		if t.col > 0 {
+			t.col--
+		}
+	case tcell.KeyRight:
+		if t.col < len(line) {
+			t.col++
+		}
+	case tcell.KeyHome:
+		t.col = 0
+	case tcell.KeyEnd:
+		t.col = len(line)
+	case tcell.KeyUp:
+		if t.Height == 1 || t.row == 0 {
+			return false
+		}
+		t.row--
+		t.clampCol()
+	case tcell.KeyDown:
+		if t.Height == 1 || t.row == len(t.lines)-1 {
+			return false
+		}
+		t.row++
+		t.clampCol()
+	case tcell.KeyEnter:
+		if t.Height == 1 {
+			return false
+		}
+		rest := append([]rune{}, line[t.col:]...)
+		t.lines[t.row] = line[:t.col]
+		t.lines = append(t.lines[:t.row+1], append([][]rune{rest}, t.lines[t.row+1:]...)...)
+		t.row++
+		t.col = 0
+	default:
+		return false
+	}
+	return true
+}
+
+func (t *TextArea) clampCol() {
+	if t.col > len(t.lines[t.row]) {
+		t.col = len(t.lines[t.row])
+	}
+}
+
+func indexOf(options []string, value string) int {
+	for i, opt := range options {
+		if opt == value {
+			return i
+		}
+	}
+	return 0
+}
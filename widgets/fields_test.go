@@ -0,0 +1,169 @@
+package widgets
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"testing"
+)
+
+func key(k tcell.Key) *tcell.EventKey {
+	return tcell.NewEventKey(k, 0, tcell.ModNone)
+}
+
+func char(r rune) *tcell.EventKey {
+	return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)
+}
+
+func TestTextAreaKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		height  int
+		keys    []*tcell.EventKey
+		want    string
+		wantRow int
+		wantCol int
+	}{
+		{"type at start", "bc", 3,
+			[]*tcell.EventKey{char('a')}, "abc", 0, 1},
+		{"backspace at start of first line", "abc", 3,
+			[]*tcell.EventKey{key(tcell.KeyBackspace2)}, "abc", 0, 0},
+		{"delete at end of last line", "ab\ncd", 3,
+			[]*tcell.EventKey{key(tcell.KeyDown), key(tcell.KeyEnd), key(tcell.KeyDelete)},
+			"ab\ncd", 1, 2},
+		{"left at start stays put", "abc", 3,
+			[]*tcell.EventKey{key(tcell.KeyLeft)}, "abc", 0, 0},
+		{"right at end stays put", "ab", 3,
+			[]*tcell.EventKey{key(tcell.KeyEnd), key(tcell.KeyRight)}, "ab", 0, 2},
+		{"down clamps to a shorter line", "abcdef\nxy", 3,
+			[]*tcell.EventKey{key(tcell.KeyEnd), key(tcell.KeyDown)}, "abcdef\nxy", 1, 2},
+		{"up keeps column on a longer line", "abcdef\nxy", 3,
+			[]*tcell.EventKey{key(tcell.KeyDown), key(tcell.KeyEnd), key(tcell.KeyUp)},
+			"abcdef\nxy", 0, 2},
+		{"up on first line", "ab\ncd", 3,
+			[]*tcell.EventKey{key(tcell.KeyUp)}, "ab\ncd", 0, 0},
+		{"down on last line", "ab\ncd", 3,
+			[]*tcell.EventKey{key(tcell.KeyDown), key(tcell.KeyDown)}, "ab\ncd", 1, 0},
+		{"enter splits the line", "abcd", 3,
+			[]*tcell.EventKey{key(tcell.KeyRight), key(tcell.KeyRight), key(tcell.KeyEnter)},
+			"ab\ncd", 1, 0},
+		{"backspace joins with the line above", "ab\ncd", 3,
+			[]*tcell.EventKey{key(tcell.KeyDown), key(tcell.KeyBackspace2)}, "abcd", 0, 2},
+		{"delete joins with the line below", "ab\ncd", 3,
+			[]*tcell.EventKey{key(tcell.KeyEnd), key(tcell.KeyDelete)}, "abcd", 0, 2},
+		{"typing after a split leaves the next line alone", "abcd", 3,
+			[]*tcell.EventKey{key(tcell.KeyRight), key(tcell.KeyRight), key(tcell.KeyEnter),
+				key(tcell.KeyUp), key(tcell.KeyEnd), char('x')},
+			"abx\ncd", 0, 3},
+		{"single line ignores enter", "ab", 1,
+			[]*tcell.EventKey{key(tcell.KeyEnter)}, "ab", 0, 0},
+		{"empty value", "", 3,
+			[]*tcell.EventKey{key(tcell.KeyBackspace2), key(tcell.KeyDelete),
+				key(tcell.KeyDown), char('z')},
+			"z", 0, 1},
+	}
+	for _, tt := range tests {
+		ta := NewTextArea(&Input{Width: 10, Height: tt.height}, tt.value, false)
+		for _, ev := range tt.keys {
+			ta.HandleKey(ev)
+		}
+		if got := ta.Value(); got != tt.want {
+			t.Errorf("%s: value %q, want %q", tt.name, got, tt.want)
+		}
+		if ta.row != tt.wantRow || ta.col != tt.wantCol {
+			t.Errorf("%s: cursor at %d,%d, want %d,%d",
+				tt.name, ta.row, ta.col, tt.wantRow, tt.wantCol)
+		}
+	}
+}
+
+func TestTextAreaSingleLinePassesArrows(t *testing.T) {
+	ta := NewTextArea(&Input{Width: 10, Height: 1}, "ab", false)
+	for _, k := range []tcell.Key{tcell.KeyUp, tcell.KeyDown, tcell.KeyEnter} {
+		if ta.HandleKey(key(k)) {
+			t.Errorf("single line textarea consumed %s", tcell.KeyNames[k])
+		}
+	}
+}
+
+func TestSelectKeys(t *testing.T) {
+	options := []string{"red", "green", "blue"}
+	tests := []struct {
+		name    string
+		options []string
+		value   string
+		keys    []*tcell.EventKey
+		want    string
+	}{
+		{"starts on value", options, "green", nil, "green"},
+		{"unknown value starts on first", options, "pink", nil, "red"},
+		{"right cycles", options, "red",
+			[]*tcell.EventKey{key(tcell.KeyRight)}, "green"},
+		{"left wraps around", options, "red",
+			[]*tcell.EventKey{key(tcell.KeyLeft)}, "blue"},
+		{"right wraps around", options, "blue",
+			[]*tcell.EventKey{key(tcell.KeyRight)}, "red"},
+		{"open, move and pick", options, "red",
+			[]*tcell.EventKey{char(' '), key(tcell.KeyDown), key(tcell.KeyDown),
+				key(tcell.KeyDown), key(tcell.KeyEnter)}, "blue"},
+		{"open and escape keeps value", options, "red",
+			[]*tcell.EventKey{char(' '), key(tcell.KeyDown), key(tcell.KeyEscape)}, "red"},
+		{"up at top of open list", options, "red",
+			[]*tcell.EventKey{char(' '), key(tcell.KeyUp), char(' ')}, "red"},
+		{"empty options", nil, "",
+			[]*tcell.EventKey{key(tcell.KeyRight), key(tcell.KeyLeft), char(' '),
+				key(tcell.KeyDown), key(tcell.KeyEnter)}, ""},
+	}
+	for _, tt := range tests {
+		sl := NewSelect(&Input{Width: 10, Height: 1}, tt.options, tt.value)
+		for _, ev := range tt.keys {
+			sl.HandleKey(ev)
+		}
+		if got := sl.Value(); got != tt.want {
+			t.Errorf("%s: value %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRadioGroupKeys(t *testing.T) {
+	options := []string{"small", "medium", "large"}
+	tests := []struct {
+		name    string
+		options []string
+		value   string
+		keys    []*tcell.EventKey
+		want    string
+	}{
+		{"starts on value", options, "large", nil, "large"},
+		{"moving doesn't select", options, "small",
+			[]*tcell.EventKey{key(tcell.KeyDown)}, "small"},
+		{"move and select", options, "small",
+			[]*tcell.EventKey{key(tcell.KeyDown), char(' ')}, "medium"},
+		{"down stops at last", options, "small",
+			[]*tcell.EventKey{key(tcell.KeyDown), key(tcell.KeyDown),
+				key(tcell.KeyDown), key(tcell.KeyDown), char(' ')}, "large"},
+		{"up stops at first", options, "medium",
+			[]*tcell.EventKey{key(tcell.KeyUp), key(tcell.KeyUp), char(' ')}, "small"},
+		{"empty options", nil, "",
+			[]*tcell.EventKey{key(tcell.KeyDown), key(tcell.KeyUp), char(' ')}, ""},
+	}
+	for _, tt := range tests {
+		r := NewRadioGroup(&Input{Width: 10, Height: 3}, tt.options, tt.value)
+		for _, ev := range tt.keys {
+			r.HandleKey(ev)
+		}
+		if got := r.Value(); got != tt.want {
+			t.Errorf("%s: value %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckboxKeys(t *testing.T) {
+	c := NewCheckbox(&Input{}, false)
+	if c.HandleKey(char('x')) || c.Value() != "false" {
+		t.Errorf("checkbox toggled on a key other than space")
+	}
+	c.HandleKey(char(' '))
+	if c.Value() != "true" {
+		t.Errorf("space didn't check the checkbox")
+	}
+}
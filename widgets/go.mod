@@ -0,0 +1,11 @@
+module github.com/rendicott/uggly-client/widgets
+
+go 1.15
+
+require (
+	github.com/gdamore/tcell/v2 v2.4.0
+	github.com/go-stack/stack v1.8.1 // indirect
+	github.com/inconshreveable/log15 v0.0.0-20201112154412-8562bdadbbac
+	github.com/mattn/go-colorable v0.1.15 // indirect
+	github.com/mattn/go-isatty v0.0.24 // indirect
+)
@@ -0,0 +1,210 @@
+// widgets implements the client side form widgets that ugform
+// does not provide (checkboxes, radio groups, select lists and
+// multiline textareas). A Form mirrors the ugform.Form API so the
+// browser can hand control to either one the same way.
+package widgets
+
+import (
+	"context"
+	"github.com/gdamore/tcell/v2"
+	"github.com/inconshreveable/log15"
+	"sort"
+)
+
+// Loggo is the global logger
+var Loggo log15.Logger
+
+// Widget is a single focusable element of a Form
+type Widget interface {
+	// Common returns the properties shared by all widgets
+	Common() *Input
+	// Value is what gets submitted to the server for this widget
+	Value() string
+	// Draw paints the widget, focused widgets show a cursor
+	Draw(s tcell.Screen, focused bool)
+	// HandleKey returns true if the widget consumed the key
+	// so the form should not act on it
+	HandleKey(ev *tcell.EventKey) bool
+}
+
+// overlay is implemented by widgets that draw outside their own
+// bounds and need the page behind them repainted after they close
+type overlay interface {
+	takeRepaint() bool
+}
+
+// Styles holds the tcell styles a widget draws with
+type Styles struct {
+	Text        tcell.Style
+	Fill        tcell.Style
+	Cursor      tcell.Style
+	Description tcell.Style
+}
+
+// Input holds the properties common to every widget
+type Input struct {
+	Name            string
+	TabOrder        int
+	Description     string
+	ShowDescription bool
+	PositionX       int
+	PositionY       int
+	Width           int
+	Height          int
+	Styles          Styles
+}
+
+// Form is a collection of widgets that can be polled for input
+type Form struct {
+	Name         string
+	SubmitAction interface{}
+	// Repaint is called when a widget closed an overlay and the
+	// content behind it needs to be drawn again
+	Repaint func()
+	s       tcell.Screen
+	widgets []Widget
+	focus   int
+	polling bool
+}
+
+// NewForm returns an empty form that draws to s
+func NewForm(s tcell.Screen) *Form {
+	return &Form{
+		s:       s,
+		widgets: make([]Widget, 0),
+	}
+}
+
+// Add appends a widget to the form keeping widgets in tab order
+func (f *Form) Add(w Widget) {
+	f.widgets = append(f.widgets, w)
+	sort.SliceStable(f.widgets, func(i, j int) bool {
+		return f.widgets[i].Common().TabOrder < f.widgets[j].Common().TabOrder
+	})
+}
+
+// ShiftXY moves every widget in the form by x and y
+func (f *Form) ShiftXY(x, y int) {
+	for _, w := range f.widgets {
+		in := w.Common()
+		in.PositionX += x
+		in.PositionY += y
+	}
+}
+
+// Start draws the form. Callers are expected to Show() the screen.
+func (f *Form) Start() {
+	for i, w := range f.widgets {
+		w.Draw(f.s, f.polling && i == f.focus)
+	}
+}
+
+// Collect returns the current value of every widget keyed by name
+func (f *Form) Collect() map[string]string {
+	values := make(map[string]string)
+	for _, w := range f.widgets {
+		values[w.Common().Name] = w.Value()
+	}
+	return values
+}
+
+func (f *Form) next(step int) {
+	if len(f.widgets) == 0 {
+		return
+	}
+	f.focus = (f.focus + step + len(f.widgets)) % len(f.widgets)
+}
+
+// Poll takes over the screen's event loop until the user submits the
+// form with Enter (or Ctrl-D from inside a textarea), leaves it with
+// Escape or ctx is cancelled. Submissions send the form name on submit
+// before interrupt is closed.
+func (f *Form) Poll(ctx context.Context, interrupt chan struct{}, submit chan string) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// PollEvent blocks so wake it up if we get cancelled
+		select {
+		case <-ctx.Done():
+			f.s.PostEvent(tcell.NewEventInterrupt(nil))
+		case <-done:
+		}
+	}()
+	f.polling = true
+	f.focus = 0
+	// redraw without focus before handing control back
+	leave := func() {
+		f.polling = false
+		f.Start()
+		f.s.Show()
+		close(interrupt)
+	}
+	f.Start()
+	f.s.Show()
+	for {
+		ev := f.s.PollEvent()
+		if ctx.Err() != nil {
+			Loggo.Debug("widget form poll cancelled", "form", f.Name)
+			leave()
+			return
+		}
+		kev, ok := ev.(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		if len(f.widgets) > 0 {
+			w := f.widgets[f.focus]
+			if w.HandleKey(kev) {
+				if o, ok := w.(overlay); ok && o.takeRepaint() && f.Repaint != nil {
+					f.Repaint()
+				}
+				f.Start()
+				f.s.Show()
+				continue
+			}
+		}
+		switch kev.Key() {
+		case tcell.KeyEscape:
+			leave()
+			return
+		case tcell.KeyTab:
+			f.next(1)
+		case tcell.KeyBacktab:
+			f.next(-1)
+		case tcell.KeyEnter, tcell.KeyCtrlD:
+			Loggo.Debug("widget form submitted", "form", f.Name)
+			submit <- f.Name
+			leave()
+			return
+		}
+		f.Start()
+		f.s.Show()
+	}
+}
+
+// drawText writes text at x, y padding with spaces out to width
+func drawText(s tcell.Screen, x, y, width int, text string, st tcell.Style) {
+	runes := []rune(text)
+	for i := 0; i < width; i++ {
+		c := ' '
+		if i < len(runes) {
+			c = runes[i]
+		}
+		s.SetContent(x+i, y, c, nil, st)
+	}
+}
+
+// drawDescription puts the description to the left of the widget
+// the same way ugform does for textboxes
+func (in *Input) drawDescription(s tcell.Screen) {
+	if !in.ShowDescription || in.Description == "" {
+		return
+	}
+	desc := []rune(in.Description)
+	x := in.PositionX - len(desc) - 1
+	if x < 0 {
+		desc = desc[-x:]
+		x = 0
+	}
+	drawText(s, x, in.PositionY, len(desc), string(desc), in.Styles.Description)
+}